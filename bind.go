@@ -0,0 +1,269 @@
+package dbx
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// formats of time values accepted by BindRequest
+var bindTimeFormats = []string{time.RFC3339Nano, DATETIME_FORMAT, "2006-01-02"}
+
+// FieldError is an error of converting a request value to a field of row
+type FieldError struct {
+	// Key is the name of value in request
+	Key    string
+	Column string
+	Err    error
+}
+
+func (this *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", this.Key, this.Err)
+}
+
+func (this *FieldError) Unwrap() error {
+	return this.Err
+}
+
+// BindError reports all field errors of BindRequest
+type BindError struct {
+	Errors []*FieldError
+}
+
+func (this *BindError) Error() string {
+	msgs := make([]string, len(this.Errors))
+	for i, e := range this.Errors {
+		msgs[i] = e.Error()
+	}
+	return "invalid request values: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the field errors for errors.As
+func (this *BindError) Unwrap() []error {
+	errs := make([]error, len(this.Errors))
+	for i, e := range this.Errors {
+		errs[i] = e
+	}
+	return errs
+}
+
+// BindRequest sets columns of row, a pointer to table row, from the body of
+// multipart, urlencoded or JSON request, values of query string are used as
+// well for form requests. A column is looked up by the form tag, json tag or
+// name of column in order. Values are converted to types of fields: numbers,
+// bools (a checkbox "on" is true), time in RFC3339, DATETIME_FORMAT or
+// 2006-01-02 format, pointers (an empty form value is nil, an empty JSON
+// string isn't), sql.Scanner, []byte and slices of multi-valued form fields.
+// A JSON body is read up to 10 MB, as net/http limits an urlencoded body.
+//
+// It returns columns in the request in the order of fields, so they can be
+// updated partially:
+//
+//	cols, err := table.BindRequest(r, &user)
+//	_, err = db.T("user").Update("id=?", id).Set(cols...).Value(&user)
+//
+// Columns which fail to be converted are not returned, and all conversion
+// errors are returned in a *BindError
+func (this *Table) BindRequest(r *http.Request, row interface{}) ([]string,
+	error) {
+	rowVal, err := this.rowValue(row)
+	if err != nil {
+		return nil, err
+	}
+
+	var lookup func(key string) (interface{}, bool)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		body := map[string]interface{}{}
+		decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body,
+			defaultMaxJSONSize))
+		decoder.UseNumber()
+		if err := decoder.Decode(&body); err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		lookup = func(key string) (interface{}, bool) {
+			v, ok := body[key]
+			return v, ok
+		}
+	} else {
+		if err := parseRequestForm(r); err != nil {
+			return nil, err
+		}
+		lookup = func(key string) (interface{}, bool) {
+			vs, ok := r.Form[key]
+			return vs, ok && len(vs) > 0
+		}
+	}
+
+	cols := []string{}
+	errs := &BindError{}
+	for _, c := range this.orderedColumns() {
+		f := this.rowType.Field(c.Index)
+		for _, key := range bindKeys(c, f) {
+			v, ok := lookup(key)
+			if !ok {
+				continue
+			}
+			if err := bindValue(rowVal.Field(c.Index), v); err != nil {
+				errs.Errors = append(errs.Errors, &FieldError{
+					Key: key, Column: c.Name, Err: err,
+				})
+			} else {
+				cols = append(cols, c.Name)
+			}
+			break
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return cols, errs
+	}
+	return cols, nil
+}
+
+// parseRequestForm parses body of multipart or urlencoded request and query
+// string to r.Form
+func parseRequestForm(r *http.Request) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		return r.ParseMultipartForm(defaultMaxMemory)
+	}
+	return r.ParseForm()
+}
+
+// bindKeys returns names of column c in request
+func bindKeys(c Column, f reflect.StructField) []string {
+	keys := []string{}
+	if c.FormName != "" {
+		keys = append(keys, c.FormName)
+	}
+	if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" &&
+		name != "-" {
+		keys = append(keys, name)
+	}
+	return append(keys, c.Name)
+}
+
+// bindValue converts a form value of []string, or a decoded JSON value to
+// field
+func bindValue(field reflect.Value, v interface{}) error {
+	isSlice := field.Kind() == reflect.Slice &&
+		field.Type().Elem().Kind() != reflect.Uint8
+	switch s := v.(type) {
+	case nil:
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	case []string:
+		if !isSlice {
+			return bindString(field, s[0])
+		}
+		items := make([]interface{}, len(s))
+		for i, item := range s {
+			items[i] = item
+		}
+		return bindValue(field, items)
+	case string:
+		return bindText(field, s, false)
+	case json.Number:
+		return bindText(field, s.String(), false)
+	case bool:
+		if field.Kind() == reflect.Ptr {
+			p := reflect.New(field.Type().Elem())
+			if err := bindValue(p.Elem(), v); err != nil {
+				return err
+			}
+			field.Set(p)
+			return nil
+		}
+		return assignValue(field, s)
+	case []interface{}:
+		if isSlice {
+			slice := reflect.MakeSlice(field.Type(), len(s), len(s))
+			for i, item := range s {
+				if err := bindValue(slice.Index(i), item); err != nil {
+					return fmt.Errorf("item %d: %v", i, err)
+				}
+			}
+			field.Set(slice)
+			return nil
+		}
+	}
+
+	// objects and arrays of other fields are decoded by encoding/json
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(field.Addr().Interface()); err != nil {
+		return fmt.Errorf("converting %s to %s: %v", data, field.Type(), err)
+	}
+	return nil
+}
+
+// bindString converts a text value of form or query string to field, an
+// empty value is NULL for pointers and sql.Scanner
+func bindString(field reflect.Value, s string) error {
+	return bindText(field, s, true)
+}
+
+// bindText converts a text value to field, an empty value is NULL for
+// pointers and sql.Scanner if emptyIsNull, e.g. not for JSON strings
+func bindText(field reflect.Value, s string, emptyIsNull bool) error {
+	if field.Kind() == reflect.Ptr {
+		if s == "" && emptyIsNull {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		p := reflect.New(field.Type().Elem())
+		if err := bindText(p.Elem(), s, emptyIsNull); err != nil {
+			return err
+		}
+		field.Set(p)
+		return nil
+	}
+
+	switch field.Type() {
+	case timeType, nullTimeType:
+		if s == "" && field.Type() == nullTimeType {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		t, ok := parseBindTime(s)
+		if !ok {
+			return fmt.Errorf("converting %q to time: unknown format", s)
+		}
+		return assignValue(field, t)
+	}
+
+	if reflect.PtrTo(field.Type()).Implements(scannerType) {
+		var src interface{} = s
+		if s == "" && emptyIsNull {
+			src = nil
+		}
+		return field.Addr().Interface().(sql.Scanner).Scan(src)
+	}
+	if field.Kind() == reflect.Bool && s == "on" {
+		field.SetBool(true)
+		return nil
+	}
+	// []byte gets the text as it is
+	return assignValue(field, s)
+}
+
+// parseBindTime parses text in one of bindTimeFormats
+func parseBindTime(s string) (time.Time, bool) {
+	for _, layout := range bindTimeFormats {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
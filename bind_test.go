@@ -0,0 +1,153 @@
+package dbx
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type BindRow struct {
+	Id       int64          `json:"id" db:"id" sqlite:"INTEGER PRIMARY KEY"`
+	Name     string         `json:"name" db:"name" form:"user_name" sqlite:"TEXT"`
+	Active   bool           `json:"active" db:"active" sqlite:"INTEGER"`
+	Score    float64        `json:"score" db:"score" sqlite:"REAL"`
+	Birthday time.Time      `json:"birthday" db:"birthday" sqlite:"TEXT"`
+	Tags     []string       `json:"tags" db:"tags" sqlite:"TEXT"`
+	Age      *int           `json:"age" db:"age" sqlite:"INTEGER"`
+	Note     sql.NullString `json:"note" db:"note" sqlite:"TEXT"`
+	Nick     *string        `json:"nick" db:"nick" sqlite:"TEXT"`
+	Data     []byte         `json:"data" db:"data" sqlite:"BLOB"`
+}
+
+func bindTable(t *testing.T) *Table {
+	table := &Table{Columns: map[string]Column{}}
+	assert.Nil(t, table.Parse("bind_row", BindRow{}))
+	return table
+}
+
+func TestBindForm(t *testing.T) {
+	assert := assert.New(t)
+	table := bindTable(t)
+
+	form := url.Values{
+		"user_name": {"eschao"}, "active": {"on"}, "score": {"9.5"},
+		"birthday": {"2000-01-02"}, "tags": {"a", "b"}, "age": {""},
+		"note": {"hi"}, "nick": {""}, "data": {"raw"},
+	}
+	r := httptest.NewRequest(http.MethodPost, "/users?id=3",
+		strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	row := BindRow{}
+	cols, err := table.BindRequest(r, &row)
+	assert.Nil(err)
+	assert.Equal([]string{"id", "name", "active", "score", "birthday", "tags",
+		"age", "note", "nick", "data"}, cols)
+	assert.Equal(int64(3), row.Id)
+	assert.Equal("eschao", row.Name)
+	assert.True(row.Active)
+	assert.Equal(9.5, row.Score)
+	assert.Equal(time.Date(2000, 1, 2, 0, 0, 0, 0, time.Local), row.Birthday)
+	assert.Equal([]string{"a", "b"}, row.Tags)
+	assert.Nil(row.Age)
+	assert.Equal(sql.NullString{String: "hi", Valid: true}, row.Note)
+	assert.Nil(row.Nick)
+	assert.Equal([]byte("raw"), row.Data)
+
+	// multipart with partial columns
+	body := bytes.Buffer{}
+	w := multipart.NewWriter(&body)
+	w.WriteField("name", "chao")
+	w.WriteField("age", "30")
+	w.Close()
+	r = httptest.NewRequest(http.MethodPost, "/users", &body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+
+	row = BindRow{}
+	cols, err = table.BindRequest(r, &row)
+	assert.Nil(err)
+	assert.Equal([]string{"name", "age"}, cols)
+	assert.Equal("chao", row.Name)
+	assert.Equal(30, *row.Age)
+
+	// the old API accepts urlencoded body as well
+	r = httptest.NewRequest(http.MethodPost, "/users",
+		strings.NewReader("user_name=e"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	m, err := table.GetColumnsMapFromForm(r)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"user_name": "e"}, m)
+}
+
+func TestBindJSON(t *testing.T) {
+	assert := assert.New(t)
+	table := bindTable(t)
+
+	r := httptest.NewRequest(http.MethodPut, "/users/1", strings.NewReader(`{
+		"id": 1, "name": "eschao", "active": true, "score": 3,
+		"birthday": "2000-01-02 03:04:05", "tags": ["a"], "age": 7,
+		"note": null, "nick": "", "unknown": 1}`))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	row := BindRow{Note: sql.NullString{String: "x", Valid: true}}
+	cols, err := table.BindRequest(r, &row)
+	assert.Nil(err)
+	assert.Equal([]string{"id", "name", "active", "score", "birthday", "tags",
+		"age", "note", "nick"}, cols)
+	assert.Equal(BindRow{
+		Id: 1, Name: "eschao", Active: true, Score: 3,
+		Birthday: time.Date(2000, 1, 2, 3, 4, 5, 0, time.Local),
+		Tags:     []string{"a"}, Age: row.Age, Nick: row.Nick,
+	}, row)
+	assert.Equal(7, *row.Age)
+	// an empty JSON string isn't NULL
+	assert.NotNil(row.Nick)
+	assert.Equal("", *row.Nick)
+
+	r = httptest.NewRequest(http.MethodPut, "/users/1", strings.NewReader("{"))
+	r.Header.Set("Content-Type", "application/json")
+	_, err = table.BindRequest(r, &row)
+	assert.NotNil(err)
+	_, err = table.BindRequest(r, row)
+	assert.NotNil(err)
+
+	// size of JSON body is limited
+	r = httptest.NewRequest(http.MethodPut, "/users/1", strings.NewReader(
+		`{"name": "`+strings.Repeat("a", defaultMaxJSONSize)+`"}`))
+	r.Header.Set("Content-Type", "application/json")
+	_, err = table.BindRequest(r, &row)
+	maxErr := &http.MaxBytesError{}
+	assert.True(errors.As(err, &maxErr))
+}
+
+func TestBindErrors(t *testing.T) {
+	assert := assert.New(t)
+	table := bindTable(t)
+
+	r := httptest.NewRequest(http.MethodPost,
+		"/users?id=x&name=e&active=maybe&birthday=yesterday", nil)
+	row := BindRow{}
+	cols, err := table.BindRequest(r, &row)
+	assert.Equal([]string{"name"}, cols)
+
+	bindErr := &BindError{}
+	assert.True(errors.As(err, &bindErr))
+	assert.Equal(3, len(bindErr.Errors))
+	assert.Equal("id", bindErr.Errors[0].Column)
+	assert.Equal("active", bindErr.Errors[1].Key)
+	assert.Equal("birthday", bindErr.Errors[2].Column)
+	assert.Contains(err.Error(), "invalid request values: id: ")
+
+	fieldErr := &FieldError{}
+	assert.True(errors.As(err, &fieldErr))
+	assert.Equal("id", fieldErr.Key)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	dbx "github.com/eschao/go-dbx"
+	"github.com/eschao/go-dbx/dbxgen"
+)
+
+// runGen generates Go structs from tables of an existing database
+func runGen(args []string) error {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	driver := fs.String("driver", dbx.DRIVER_SQLITE3, "database driver: "+
+		dbx.DRIVER_SQLITE3+" or "+dbx.DRIVER_MYSQL)
+	dsn := fs.String("dsn", "", "data source name, e.g. file path of SQLite")
+	pkg := fs.String("pkg", "models", "package name of generated file")
+	out := fs.String("o", "", "output file, default is stdout")
+	tables := fs.String("tables", "", "comma separated tables, default is all")
+	nullTypes := fs.Bool("null-types", false,
+		"use sql.Null* types for nullable columns instead of pointers")
+	register := fs.String("register", "RegisterTables",
+		"name of function registering tables")
+	fs.Parse(args)
+
+	if *dsn == "" {
+		return fmt.Errorf("-dsn is required")
+	}
+	dbx.SetLogger(nil)
+	db := dbx.NewDatabase()
+	if err := db.Open(*driver, *dsn); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	names := []string{}
+	if *tables != "" {
+		names = strings.Split(*tables, ",")
+	}
+	schemas, err := db.Introspect(names...)
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.Buffer{}
+	err = dbxgen.Structs(&buf, schemas, &dbxgen.StructOptions{
+		Package: *pkg, Driver: *driver, NullTypes: *nullTypes,
+		RegisterFunc: *register,
+	})
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(*out, buf.Bytes(), 0644)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/eschao/go-dbx/dbxgen"
+)
+
+// runHelpers generates typed helpers of table structs, it's used by
+// go generate:
+//
+//	//go:generate dbx helpers -type User,UserLogin:user_login
+func runHelpers(args []string) error {
+	fs := flag.NewFlagSet("helpers", flag.ExitOnError)
+	types := fs.String("type", "", "comma separated struct types, a type "+
+		"can be followed by :table, default table is snake case of type")
+	dir := fs.String("dir", ".", "package directory of structs")
+	out := fs.String("o", "dbx_helpers.go", "output file in package directory")
+	fs.Parse(args)
+
+	if *types == "" {
+		return fmt.Errorf("-type is required")
+	}
+	names := []string{}
+	tables := map[string]string{}
+	for _, t := range strings.Split(*types, ",") {
+		kv := strings.SplitN(strings.TrimSpace(t), ":", 2)
+		names = append(names, kv[0])
+		if len(kv) == 2 {
+			tables[kv[0]] = kv[1]
+		}
+	}
+
+	pkg, structs, err := dbxgen.ParseStructs(*dir, names, tables)
+	if err != nil {
+		return err
+	}
+	buf := bytes.Buffer{}
+	if err := dbxgen.Helpers(&buf, pkg, structs); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(*dir, *out), buf.Bytes(), 0644)
+}
@@ -0,0 +1,60 @@
+// Command dbx generates code and schema for dbx:
+//
+//	dbx gen -driver mysql -dsn 'user:passwd@tcp(host:3306)/db' -pkg models
+//	dbx helpers -type User,UserLogin:user_login
+//	dbx schema -pkg ./models -dialect mysql -o schema.sql
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command is a sub command of dbx
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"gen": {"generate Go structs from tables of an existing database", runGen},
+	"helpers": {"generate typed helpers of table structs for go generate",
+		runHelpers},
+	"schema": {"dump schema of registered tables as SQL statements",
+		runSchema},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: dbx <command> [flags]\n\ncommands:\n")
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nrun 'dbx <command> -h' for flags of command\n")
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "dbx: unknown command %s\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "dbx %s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+
+	dbx "github.com/eschao/go-dbx"
+)
+
+// schemaMain is the program dumping schema of tables registered by a
+// function of the given package
+var schemaMain = template.Must(template.New("schema").Parse(`package main
+
+import (
+	"fmt"
+	"os"
+
+	dbx "github.com/eschao/go-dbx"
+	models {{.Package}}
+)
+
+func main() {
+	dbx.SetLogger(nil)
+	db := dbx.NewDatabase()
+	if err := models.{{.Func}}(db); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := db.DumpSchema(os.Stdout, {{.Dialect}}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`))
+
+// runSchema dumps schema of tables registered by a function of a package in
+// the current module, e.g. the function generated by dbx gen. It builds and
+// runs a temporary program in the current directory:
+//
+//	dbx schema -pkg ./models -dialect mysql -o schema.sql
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	pkg := fs.String("pkg", ".", "package of the register function, an "+
+		"import path or a relative directory")
+	fn := fs.String("func", "RegisterTables", "function registering tables "+
+		"with signature func(*dbx.Database) error")
+	dialect := fs.String("dialect", dbx.DRIVER_SQLITE3, "SQL dialect: "+
+		dbx.DRIVER_SQLITE3+", "+dbx.DRIVER_MYSQL+" or "+dbx.DRIVER_POSTGRE)
+	out := fs.String("o", "", "output file, default is stdout")
+	fs.Parse(args)
+
+	path, err := exec.Command("go", "list", "-f", "{{.ImportPath}}",
+		*pkg).Output()
+	if err != nil {
+		return fmt.Errorf("can't find package %s: %v", *pkg, err)
+	}
+
+	dir, err := ioutil.TempDir(".", "dbxschema")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	src := bytes.Buffer{}
+	if err := schemaMain.Execute(&src, map[string]string{
+		"Package": strconv.Quote(strings.TrimSpace(string(path))),
+		"Func":    *fn,
+		"Dialect": strconv.Quote(*dialect),
+	}); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(dir+"/main.go", src.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	schema := bytes.Buffer{}
+	cmd := exec.Command("go", "run", "./"+dir)
+	cmd.Stdout = &schema
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(schema.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(*out, schema.Bytes(), 0644)
+}
@@ -1,9 +1,14 @@
 package dbx
 
+import (
+	"database/sql"
+)
+
 const (
-	USER_TABLE       = "user"
-	USER_LOGIN_TABLE = "user_login"
-	USER_OAUTH_TABLE = "user_oauth"
+	USER_TABLE         = "user"
+	USER_LOGIN_TABLE   = "user_login"
+	USER_OAUTH_TABLE   = "user_oauth"
+	USER_PROFILE_TABLE = "user_profile"
 )
 
 type User struct {
@@ -12,6 +17,9 @@ type User struct {
 	Nickname   string `json:"nickname"    db:"nickname"    sqlite:"TEXT"                              mysql:"varchar(64) NOT NULL DEFAULT ''"`
 	Password   string `json:"password"    db:"password"    sqlite:"TEXT"                              mysql:"varchar(32) NOT NULL DEFAULT ''"`
 	UpdateTime string `json:"update_time" db:"update_time" sqlite:"INTEGER"                           mysql:"datetime NOT NULL DEFAULT '2000-01-01 00:00:00'"`
+
+	Logins []UserLogin `json:"logins,omitempty" dbx:"hasMany:user_login,foreignKey:userid,references:userid"`
+	OAuth  *UserOAuth  `json:"oauth,omitempty"  dbx:"hasOne:user_oauth,foreignKey:userid,references:userid"`
 }
 
 type UserLogin struct {
@@ -21,6 +29,8 @@ type UserLogin struct {
 	LastLogin  string `json:"last_login"  column:"last_login"  sqlite:"INTEGER"                           mysql:"datetime NOT NULL DEFAULT '2000-01-01 00:00:00'"`
 	LastIP     int64  `json:"last_ip"     column:"last_ip"     sqlite:"INTEGER"                           mysql:"int NOT NULL DEFAULT ''"`
 	UpdateTime string `json:"update_time" column:"update_time" sqlite:"INTEGER"                           mysql:"datetime NOT NULL DEFAULT '2000-01-01 00:00:00'"`
+
+	User *User `json:"user,omitempty" dbx:"belongsTo:user,foreignKey:userid,references:userid"`
 }
 
 type UserOAuth struct {
@@ -32,6 +42,21 @@ type UserOAuth struct {
 	Token      string `json:"token"       column:"token"       sqlite:"TEXT"                              mysql:"varchar(64) NOT NULL DEFAULT ''"`
 	ExpireTime string `json:"expire_time" column:"expire_time" sqlite:"INTEGER"                           mysql:"datetime NOT NULL DEFAULT '2000-01-01 00:00:00'"`
 	UpdateTime string `json:"update_time" column:"update_time" sqlite:"INTEGER"                           mysql:"datetime NOT NULL DEFAULT '2000-01-01 00:00:00'"`
+
+	Owner User `json:"-"`
+}
+
+type UserProfile struct {
+	Id     int64         `json:"id"     db:"id"     sqlite:"INTEGER PRIMARY KEY AUTOINCREMENT" mysql:"int NOT NULL PRIMARY KEY AUTO_INCREMENT"`
+	Userid string        `json:"userid" db:"userid" sqlite:"TEXT NOT NULL"                     mysql:"varchar(32) NOT NULL"`
+	Email  *string       `json:"email"  db:"email"  sqlite:"TEXT"                              mysql:"varchar(64)"`
+	Age    sql.NullInt64 `json:"age"    db:"age"    sqlite:"INTEGER"                           mysql:"int"`
+	Bio    string        `json:"bio"    db:"bio"    sqlite:"TEXT"                              mysql:"text"`
+
+	Version   int64         `json:"version"    db:"version"    sqlite:"INTEGER NOT NULL DEFAULT 0" mysql:"int NOT NULL DEFAULT 0" dbx:"version"`
+	CreatedAt int64         `json:"created_at" db:"created_at" sqlite:"INTEGER" mysql:"bigint NOT NULL DEFAULT 0"                          dbx:"autoCreateTime"`
+	UpdatedAt string        `json:"updated_at" db:"updated_at" sqlite:"INTEGER" mysql:"datetime NOT NULL DEFAULT '2000-01-01 00:00:00'" dbx:"autoUpdateTime"`
+	DeletedAt sql.NullInt64 `json:"deleted_at" db:"deleted_at" sqlite:"INTEGER" mysql:"bigint"                                      dbx:"softDelete"`
 }
 
 var TestUsers = []User{
@@ -1,12 +1,16 @@
 package dbx
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
 	"reflect"
+	"sort"
 	"strings"
-	
+	"sync"
+	"time"
+
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -19,6 +23,8 @@ const (
 
 const (
 	defaultMaxMemory = 32 << 20 // 32 MB
+	// max size of JSON body, the same as urlencoded body of net/http
+	defaultMaxJSONSize = 10 << 20 // 10 MB
 )
 
 var dbLogger = func(sql string) {
@@ -35,40 +41,104 @@ func SetLogger(logger func(string)) {
 //}
 
 type Column struct {
-	Name            string
-	FormName        string
-	Index           int
-	Sqlite          string
-	Mysql           string
-	Postgre         string
-	IsPrimaryKey    bool
-	IsAutoIncrement bool
+	Name             string
+	FormName         string
+	Index            int
+	Sqlite           string
+	Mysql            string
+	Postgre          string
+	IsPrimaryKey     bool
+	IsAutoIncrement  bool
+	IsSoftDelete     bool
+	IsAutoCreateTime bool
+	IsAutoUpdateTime bool
+	IsVersion        bool
+}
+
+// SQL returns the column definition of given driver
+func (this *Column) SQL(driver string) string {
+	switch driver {
+	case DRIVER_SQLITE3:
+		return this.Sqlite
+	case DRIVER_MYSQL:
+		return this.Mysql
+	case DRIVER_POSTGRE:
+		return this.Postgre
+	}
+	return ""
 }
 
+// Table is a registered table whose columns are looked up by name in
+// Columns, and kept in the order of struct fields for SQL generation
 type Table struct {
-	Name    string
-	Columns map[string]Column
-	//RowType reflect.Type
+	Name      string
+	Columns   map[string]Column
+	Relations map[string]Relation
+	Indexes   []TableIndex
+	rowType   reflect.Type
+	columns   []Column
+}
+
+// OrderedColumns returns a copy of columns of table in the order of struct
+// fields
+func (this *Table) OrderedColumns() []Column {
+	cols := this.orderedColumns()
+	return append(make([]Column, 0, len(cols)), cols...)
+}
+
+// orderedColumns returns columns of table in the order of struct fields, the
+// returned slice is shared by table and must not be changed
+func (this *Table) orderedColumns() []Column {
+	if len(this.columns) == len(this.Columns) {
+		return this.columns
+	}
+
+	// the table isn't parsed, e.g. Columns is set by caller
+	cols := make([]Column, 0, len(this.Columns))
+	for _, c := range this.Columns {
+		cols = append(cols, c)
+	}
+	sort.Slice(cols, func(i, j int) bool {
+		return cols[i].Index < cols[j].Index
+	})
+	return cols
 }
 
+// ColumnNames returns names of columns in the order of struct fields
 func (this *Table) ColumnNames() []string {
-	size := len(this.Columns)
-	names := make([]string, size, size)
-	i := 0
-	for k, _ := range this.Columns {
-		names[i] = k
-		i++
+	cols := this.orderedColumns()
+	names := make([]string, len(cols), len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
 	}
 	return names
 }
 
+// RowType returns the struct type of table rows
+func (this *Table) RowType() reflect.Type {
+	return this.rowType
+}
+
+// rowValue returns the struct value of row which must be a non-nil pointer
+// to the row type of table, or a type defined on it
+func (this *Table) rowValue(row interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(row)
+	if v.Kind() != reflect.Ptr || v.IsNil() ||
+		v.Elem().Kind() != reflect.Struct ||
+		!v.Elem().Type().ConvertibleTo(this.rowType) {
+		return reflect.Value{}, fmt.Errorf("row argument must be a non-nil "+
+			"*%s of %s table, not %T", this.rowType, this.Name, row)
+	}
+	return v.Elem(), nil
+}
+
+// ColumnIndexes returns field indexes of columns in the order of struct
+// fields
 func (this *Table) ColumnIndexes() []int {
-	size := len(this.Columns)
-	indexes := make([]int, size, size)
-	i := 0
-	for _, v := range this.Columns {
-		indexes[i] = v.Index
-		i++
+	cols := this.orderedColumns()
+	indexes := make([]int, len(cols), len(cols))
+	for i, c := range cols {
+		indexes[i] = c.Index
 	}
 	return indexes
 }
@@ -77,11 +147,11 @@ func (this *Table) GetColumnsFromForm(r *http.Request) (
 	[]string, []interface{}, error) {
 	columns := []string{}
 	values := []interface{}{}
-	if err := r.ParseMultipartForm(defaultMaxMemory); err != nil {
+	if err := parseRequestForm(r); err != nil {
 		return columns, values, err
 	}
 
-	for _, c := range this.Columns {
+	for _, c := range this.orderedColumns() {
 		name := c.FormName
 		if name == "" {
 			name = c.Name
@@ -99,11 +169,11 @@ func (this *Table) GetColumnsFromForm(r *http.Request) (
 func (this *Table) GetColumnsMapFromForm(r *http.Request) (
 	map[string]interface{}, error) {
 	columns := map[string]interface{}{}
-	if err := r.ParseMultipartForm(defaultMaxMemory); err != nil {
+	if err := parseRequestForm(r); err != nil {
 		return columns, err
 	}
 
-	for _, c := range this.Columns {
+	for _, c := range this.orderedColumns() {
 		name := c.FormName
 		if name == "" {
 			name = c.Name
@@ -127,8 +197,13 @@ func (this *Table) Parse(name string, table interface{}) error {
 		return fmt.Errorf("table is not a struct type: %v", v.Kind())
 	}
 
+	if this.Relations == nil {
+		this.Relations = map[string]Relation{}
+	}
+
 	for i := 0; i < v.NumField(); i++ {
 		f := v.Field(i)
+		opts := parseTagOptions(f.Tag.Get("dbx"))
 		col := f.Tag.Get("column")
 		if col == "" {
 			col = f.Tag.Get("col")
@@ -137,6 +212,13 @@ func (this *Table) Parse(name string, table interface{}) error {
 			col = f.Tag.Get("db")
 		}
 		if col == "" {
+			rel, err := parseRelation(f, i, opts)
+			if err != nil {
+				return err
+			}
+			if rel != nil {
+				this.Relations[rel.Name] = *rel
+			}
 			continue
 		}
 
@@ -158,10 +240,21 @@ func (this *Table) Parse(name string, table interface{}) error {
 				isAutoIncrement = true
 			}
 		}
+		// autoIncrement option marks a column assigned by database without
+		// AUTOINCREMENT, e.g. INTEGER PRIMARY KEY of SQLite aliasing rowid
+		if _, ok := opts["autoIncrement"]; ok {
+			isAutoIncrement = true
+		}
 
 		mysql := f.Tag.Get("mysql")
 		if mysql != "" {
 			s := strings.ToLower(mysql)
+			// columns only defined for mysql take attributes from it
+			if sqlite == "" {
+				isPrimaryKey = strings.Contains(s, "primary key")
+				isAutoIncrement = isAutoIncrement ||
+					strings.Contains(s, "auto_increment")
+			}
 			if sqlite != "" && isPrimaryKey != strings.Contains(s, "primary key") {
 				return fmt.Errorf("column %s has different 'primary key' attribute",
 					col)
@@ -177,12 +270,50 @@ func (this *Table) Parse(name string, table interface{}) error {
 			return fmt.Errorf("column %s does not have sql definition", col)
 		}
 
+		_, isSoftDelete := opts["softDelete"]
+		if isSoftDelete {
+			if this.SoftDeleteColumn() != "" {
+				return fmt.Errorf("table has more than one soft delete column")
+			}
+			if !isNullableType(f.Type) {
+				return fmt.Errorf("soft delete column %s must be a nullable type",
+					col)
+			}
+		}
+
+		_, isAutoCreateTime := opts["autoCreateTime"]
+		_, isAutoUpdateTime := opts["autoUpdateTime"]
+		if isAutoCreateTime || isAutoUpdateTime {
+			if _, err := timestampValue(Column{}, f.Type, "", time.Time{}); err != nil {
+				return fmt.Errorf("column %s: %v", col, err)
+			}
+		}
+
+		_, isVersion := opts["version"]
+		if isVersion {
+			if this.VersionColumn() != "" {
+				return fmt.Errorf("table has more than one version column")
+			}
+			switch f.Type.Kind() {
+			case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint,
+				reflect.Uint32, reflect.Uint64:
+			default:
+				return fmt.Errorf("version column %s must be an integer type", col)
+			}
+		}
+
 		if _, ok := this.Columns[col]; ok {
 			return fmt.Errorf("column %s is redefined", col)
 		}
-		this.Columns[col] = Column{
+		if err := this.parseIndex(name, col, opts); err != nil {
+			return err
+		}
+		c := Column{
 			col, form, i, sqlite, mysql, postgre, isPrimaryKey, isAutoIncrement,
+			isSoftDelete, isAutoCreateTime, isAutoUpdateTime, isVersion,
 		}
+		this.Columns[col] = c
+		this.columns = append(this.columns, c)
 	}
 
 	if len(this.Columns) < 1 {
@@ -190,43 +321,61 @@ func (this *Table) Parse(name string, table interface{}) error {
 	}
 
 	this.Name = name
+	this.rowType = v
 	return nil
 }
 
-func (this *Table) CreateSQL(driver string) (string, error) {
-	cols := []string{}
-	for k, v := range this.Columns {
-		if driver == DRIVER_SQLITE3 {
-			if v.Sqlite == "" {
-				return "", fmt.Errorf(
-					"%s column of %s table has no definition for sqlite", k, this.Name)
-			}
-			cols = append(cols, k+" "+v.Sqlite)
-		} else if driver == DRIVER_MYSQL {
-			if v.Mysql == "" {
-				return "", fmt.Errorf(
-					"%s column of %s table has no definition for mysql", k, this.Name)
-			}
-			cols = append(cols, k+" "+v.Mysql)
-		} else if driver == DRIVER_POSTGRE {
-			if v.Postgre == "" {
-				return "", fmt.Errorf(
-					"%s column of %s table has no definition for postgre", k, this.Name)
-			}
-			cols = append(cols, k+" "+v.Postgre)
+// PrimaryKey returns the name of primary key column, empty if table has no
+// primary key
+func (this *Table) PrimaryKey() string {
+	for _, c := range this.orderedColumns() {
+		if c.IsPrimaryKey {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// parseTagOptions parses dbx tag options like: "hasMany:user_login,
+// foreignKey:userid", an option without value is mapped to empty string
+func parseTagOptions(tag string) map[string]string {
+	opts := map[string]string{}
+	for _, s := range strings.Split(tag, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		kv := strings.SplitN(s, ":", 2)
+		if len(kv) == 2 {
+			opts[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
 		} else {
-			return "", fmt.Errorf("unsupportted driver %s", driver)
+			opts[s] = ""
 		}
 	}
+	return opts
+}
 
+// CreateSQL returns the statement creating table of driver, columns are in
+// the order of struct fields
+func (this *Table) CreateSQL(driver string) (string, error) {
+	defs, err := this.columnDefs(driver)
+	if err != nil {
+		return "", err
+	}
 	return "CREATE TABLE IF NOT EXISTS " + this.Name + "(" +
-		strings.Join(cols, ",") + ")", nil
+		strings.Join(defs, ",") + ")", nil
 }
 
 type Database struct {
-	driver string
-	db     *sql.DB
-	tables map[string]Table
+	driver       string
+	db           *sql.DB
+	tables       map[string]Table
+	nullAsZero   bool
+	clock        func() time.Time
+	logger       Logger
+	slowQuery    *SlowQueryOptions
+	interceptors []Interceptor
+	dryRun       *dryRunRecorder
 }
 
 func NewDatabase() *Database {
@@ -257,6 +406,39 @@ func (this *Database) DriverName() string {
 	return this.driver
 }
 
+// SetNullAsZero sets whether NULL columns are scanned as the zero value of
+// struct fields by default, it can be enabled per query by NullAsZero()
+func (this *Database) SetNullAsZero(enable bool) {
+	this.nullAsZero = enable
+}
+
+// SetClock sets the clock used for timestamp columns, e.g. a fixed time in
+// tests. nil resets it to time.Now
+func (this *Database) SetClock(clock func() time.Time) {
+	this.clock = clock
+}
+
+// SetLogger sets the logger receiving events of statements executed in
+// database, it overrides the package logger set by SetLogger. nil resets it
+func (this *Database) SetLogger(logger Logger) {
+	this.logger = logger
+}
+
+// now returns current time of database clock
+func (this *Database) now() time.Time {
+	if this != nil && this.clock != nil {
+		return this.clock()
+	}
+	return time.Now()
+}
+
+// OpenDB uses an opened database, e.g. one of a mock driver. The driver
+// decides SQL dialect of statements
+func (this *Database) OpenDB(driver string, db *sql.DB) {
+	this.driver = driver
+	this.db = db
+}
+
 func (this *Database) OpenSQLite(dbFile string) error {
 	db, err := sql.Open(DRIVER_SQLITE3, dbFile)
 	if err == nil {
@@ -289,6 +471,26 @@ func (this *Database) RegisterTable(name string, table interface{}) error {
 	return nil
 }
 
+// RegisterRelation registers a relationship of the given table, it is the
+// same as declaring it with dbx tag on the relation field
+func (this *Database) RegisterRelation(table string, rel Relation) error {
+	t, ok := this.tables[table]
+	if !ok {
+		return fmt.Errorf("%s table is not registered", table)
+	}
+
+	f, ok := t.rowType.FieldByName(rel.Name)
+	if !ok || len(f.Index) != 1 {
+		return fmt.Errorf("%s table has no field %s", table, rel.Name)
+	}
+	rel.Index = f.Index[0]
+	if err := rel.validate(f.Type); err != nil {
+		return err
+	}
+	t.Relations[rel.Name] = rel
+	return nil
+}
+
 func (this *Database) GetTableSchema(name string) (Table, error) {
 	table, ok := this.tables[name]
 	if ok {
@@ -297,21 +499,23 @@ func (this *Database) GetTableSchema(name string) (Table, error) {
 	return Table{}, nil
 }
 
+// TableNames returns names of registered tables in alphabetical order
+func (this *Database) TableNames() []string {
+	names := make([]string, 0, len(this.tables))
+	for k := range this.tables {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (this *Database) CreateTables() error {
 	if this.db == nil {
 		return fmt.Errorf("no opened database")
 	}
 
-	for _, v := range this.tables {
-		sql, err := v.CreateSQL(this.driver)
-		if err != nil {
-			return err
-		}
-		if dbLogger != nil {
-			dbLogger(sql)
-		}
-
-		if _, err := this.db.Exec(sql); err != nil {
+	for _, name := range this.TableNames() {
+		if err := this.createTable(this.tables[name]); err != nil {
 			return err
 		}
 	}
@@ -327,16 +531,24 @@ func (this *Database) CreateTable(name string) error {
 	if !ok {
 		return fmt.Errorf("%s table is not registered", name)
 	}
+	return this.createTable(t)
+}
+
+// createTable creates table and its indexes
+func (this *Database) createTable(t Table) error {
 	sql, err := t.CreateSQL(this.driver)
 	if err != nil {
 		return err
 	}
-	if dbLogger != nil {
-		dbLogger(sql)
+	if _, err := this.session().exec(t.Name, OP_CREATE, sql); err != nil {
+		return err
 	}
-
-	_, err = this.db.Exec(sql)
-	return err
+	for _, q := range t.IndexSQL(this.driver) {
+		if _, err := this.session().exec(t.Name, OP_CREATE, q); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (this *Database) DropTable(name string) error {
@@ -344,7 +556,7 @@ func (this *Database) DropTable(name string) error {
 		return fmt.Errorf("no opened database")
 	}
 
-	_, err := this.db.Exec("DROP TABLE " + name)
+	_, err := this.session().exec(name, OP_DROP, "DROP TABLE "+name)
 	return err
 }
 
@@ -355,8 +567,13 @@ func (this *Database) T(name string) *SQLExecutor {
 		err = fmt.Errorf("%s table is not registered", name)
 	}
 
-	return &SQLExecutor{
-		table: &t, db: this.db, err: err,
+	return &SQLExecutor{sqlSession: *this.session(), table: &t, err: err}
+}
+
+// session returns a session running statements in database
+func (this *Database) session() *sqlSession {
+	return &sqlSession{
+		db: this.db, database: this,
 		tableGetter: func(name string) *Table {
 			t, _ := this.tables[name]
 			return &t
@@ -365,41 +582,67 @@ func (this *Database) T(name string) *SQLExecutor {
 }
 
 func (this *Database) Begin() (*Transaction, error) {
-	if this.db == nil {
-		return nil, fmt.Errorf("no opened database")
-	}
-
-	tx, err := this.db.Begin()
-	if err != nil {
-		return nil, err
-	}
-	return &Transaction{db: this, tx: tx}, nil
+	return this.BeginTx(context.Background(), nil)
 }
 
-//
 // Database Transaction
-//
 type Transaction struct {
-	tx *sql.Tx
-	db *Database
+	tx        *sql.Tx
+	db        *Database
+	ctx       context.Context
+	savepoint string
+	depth     int
+
+	// pinned connection of read-only SQLite transaction
+	conn        *sql.Conn
+	stopRelease func() bool
+	releaseOnce sync.Once
 }
 
+// Tx returns the underlying transaction, which should still be ended by Commit
+// or Rollback of Transaction. It's nil for a read-only SQLite transaction, as
+// its pinned connection must be reset and released by Commit or Rollback
 func (this *Transaction) Tx() *sql.Tx {
+	if this.conn != nil {
+		return nil
+	}
 	return this.tx
 }
 
+// Commit commits the transaction, or releases the savepoint if it's a nested
+// transaction
 func (this *Transaction) Commit() error {
 	if this.tx == nil {
 		return fmt.Errorf("Nil transaction")
 	}
-	return this.tx.Commit()
+	if this.savepoint != "" {
+		return this.releaseSavepoint()
+	}
+	return this.db.intercept(this.call(OP_COMMIT), func() error {
+		err := this.tx.Commit()
+		if this.conn != nil {
+			this.releaseConn()
+		}
+		return err
+	})
 }
 
+// Rollback rolls back the transaction, or rolls back to the savepoint if
+// it's a nested transaction
 func (this *Transaction) Rollback() error {
 	if this.tx == nil {
 		return fmt.Errorf("Nil transaction")
 	}
-	return this.tx.Rollback()
+	if this.savepoint != "" {
+		return this.rollbackSavepoint()
+	}
+	return this.db.intercept(this.call(OP_ROLLBACK), func() error {
+		err := this.tx.Rollback()
+		if this.conn != nil {
+			this.releaseConn()
+		}
+		return err
+	})
 }
 
 func (this *Transaction) T(name string) *SQLExecutor {
@@ -409,11 +652,14 @@ func (this *Transaction) T(name string) *SQLExecutor {
 		err = fmt.Errorf("table %s is not registered", name)
 	}
 
-	return &SQLExecutor{
-		table: &t, tx: this.tx, err: err,
-		tableGetter: func(name string) *Table {
-			t, _ := this.db.tables[name]
-			return &t
-		},
-	}
+	return &SQLExecutor{sqlSession: *this.session(), table: &t, err: err}
+}
+
+// session returns a session running statements in transaction
+func (this *Transaction) session() *sqlSession {
+	s := this.db.session()
+	s.db = nil
+	s.tx = this.tx
+	s.ctx = this.Context()
+	return s
 }
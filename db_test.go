@@ -4,9 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
 )
 
 const TEST_DB_FILE = "test_db.db"
@@ -59,6 +61,9 @@ func prepare() error {
 	if err := tDatabase.RegisterTable(USER_OAUTH_TABLE, &UserOAuth{}); err != nil {
 		return fmt.Errorf("Can't register table: UserOAuth. Error: %s", err.Error())
 	}
+	if err := tDatabase.RegisterTable(USER_PROFILE_TABLE, &UserProfile{}); err != nil {
+		return fmt.Errorf("Can't register table: UserProfile. Error: %s", err.Error())
+	}
 	return nil
 }
 
@@ -81,3 +86,96 @@ func TestMain(m *testing.M) {
 	}
 	cleanUp()
 }
+
+func TestOrderedColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	table, err := tDatabase.GetTableSchema(USER_TABLE)
+	assert.Nil(err)
+	names := []string{"id", "userid", "nickname", "password", "update_time"}
+	assert.Equal(names, table.ColumnNames())
+	assert.Equal([]int{0, 1, 2, 3, 4}, table.ColumnIndexes())
+
+	// generated SQL is the same between runs
+	create, err := table.CreateSQL(DRIVER_SQLITE3)
+	assert.Nil(err)
+	assert.True(strings.HasPrefix(create,
+		"CREATE TABLE IF NOT EXISTS user(id INTEGER PRIMARY KEY AUTOINCREMENT,"+
+			"userid TEXT NOT NULL,"))
+	insert, _, err := tDatabase.T(USER_TABLE).InsertSQL(&User{})
+	assert.Nil(err)
+	assert.Equal("INSERT INTO user(userid,nickname,password,update_time) "+
+		"VALUES(?,?,?,?)", insert)
+	for i := 0; i < 10; i++ {
+		q, _ := table.CreateSQL(DRIVER_SQLITE3)
+		assert.Equal(create, q)
+		q, _, _ = tDatabase.T(USER_TABLE).InsertSQL(&User{})
+		assert.Equal(insert, q)
+		q, _, _ = tDatabase.T(USER_TABLE).SelectAll().ToSQL()
+		assert.Equal("SELECT id,userid,nickname,password,update_time FROM user",
+			q)
+	}
+
+	// changing the returned columns doesn't change table
+	cols := table.OrderedColumns()
+	cols[0].Name = "changed"
+	assert.Equal(names, table.ColumnNames())
+
+	// columns set by caller are ordered by field index
+	manual := Table{Name: "manual", Columns: map[string]Column{
+		"b": {Name: "b", Index: 1}, "a": {Name: "a", Index: 0},
+		"c": {Name: "c", Index: 2},
+	}}
+	assert.Equal([]string{"a", "b", "c"}, manual.ColumnNames())
+}
+
+type RowidUser struct {
+	Id   int64  `db:"id" sqlite:"INTEGER PRIMARY KEY" mysql:"int PRIMARY KEY AUTO_INCREMENT" dbx:"autoIncrement"`
+	Name string `db:"name" sqlite:"TEXT" mysql:"varchar(32)"`
+}
+
+func TestRowidAlias(t *testing.T) {
+	assert := assert.New(t)
+
+	db := NewDatabase()
+	assert.Nil(db.OpenSQLite(":memory:"))
+	defer db.Close()
+	db.DB().SetMaxOpenConns(1)
+	assert.Nil(db.RegisterTable("rowid_user", RowidUser{}))
+	assert.Nil(db.CreateTables())
+
+	// values of INTEGER PRIMARY KEY are assigned by sqlite
+	assert.True(db.tables["rowid_user"].Columns["id"].IsAutoIncrement)
+	for i := 0; i < 2; i++ {
+		_, err := db.T("rowid_user").Insert(&RowidUser{Name: "u"})
+		assert.Nil(err)
+	}
+	n, err := db.T("rowid_user").Count("id IN (1,2)")
+	assert.Nil(err)
+	assert.Equal(2, n)
+
+	tables, err := db.Introspect("rowid_user")
+	assert.Nil(err)
+	id := tables[0].Column("id")
+	assert.True(id.IsAutoIncrement)
+	assert.True(id.IsRowidAlias)
+
+	// ids of INTEGER PRIMARY KEY without autoIncrement option are inserted
+	type ManualUser struct {
+		Id   int64  `db:"id" sqlite:"INTEGER PRIMARY KEY"`
+		Name string `db:"name" sqlite:"TEXT"`
+	}
+	assert.Nil(db.RegisterTable("manual_user", ManualUser{}))
+	assert.Nil(db.CreateTable("manual_user"))
+	assert.False(db.tables["manual_user"].Columns["id"].IsAutoIncrement)
+	_, err = db.T("manual_user").Insert(&ManualUser{Id: 42, Name: "u"})
+	assert.Nil(err)
+	n, err = db.T("manual_user").Count("id=?", 42)
+	assert.Nil(err)
+	assert.Equal(1, n)
+
+	table := &Table{Columns: map[string]Column{}}
+	assert.NotNil(table.Parse("manual_id", struct {
+		Id int64 `db:"id" sqlite:"INTEGER PRIMARY KEY" mysql:"int PRIMARY KEY AUTO_INCREMENT"`
+	}{}))
+}
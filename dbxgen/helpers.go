@@ -0,0 +1,197 @@
+package dbxgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Field is a column field of table struct
+type Field struct {
+	Name         string
+	Column       string
+	Type         string
+	IsPrimaryKey bool
+	IsUnique     bool
+}
+
+// Struct is a table struct parsed from Go source
+type Struct struct {
+	Name   string
+	Table  string
+	Fields []Field
+}
+
+// ParseStructs parses the given struct types with dbx tags from Go files of
+// package dir, tables maps type name to table name, the default table name
+// is the snake case of type name. It returns the package name and structs
+func ParseStructs(dir string, types []string, tables map[string]string) (
+	string, []Struct, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(pkgs) != 1 {
+		return "", nil, fmt.Errorf("%s must have one package", dir)
+	}
+
+	pkg := ""
+	specs := map[string]*ast.StructType{}
+	for name, p := range pkgs {
+		pkg = name
+		files := make([]string, 0, len(p.Files))
+		for f := range p.Files {
+			files = append(files, f)
+		}
+		sort.Strings(files)
+		for _, f := range files {
+			ast.Inspect(p.Files[f], func(n ast.Node) bool {
+				if ts, ok := n.(*ast.TypeSpec); ok {
+					if st, ok := ts.Type.(*ast.StructType); ok {
+						specs[ts.Name.Name] = st
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	structs := make([]Struct, 0, len(types))
+	for _, name := range types {
+		st, ok := specs[name]
+		if !ok {
+			return "", nil, fmt.Errorf("struct %s is not found in %s", name,
+				filepath.Clean(dir))
+		}
+		table := tables[name]
+		if table == "" {
+			table = SnakeName(name)
+		}
+		s, err := parseStruct(fset, name, table, st)
+		if err != nil {
+			return "", nil, err
+		}
+		structs = append(structs, *s)
+	}
+	return pkg, structs, nil
+}
+
+// parseStruct parses column fields of struct as dbx.Table.Parse does
+func parseStruct(fset *token.FileSet, name, table string,
+	st *ast.StructType) (*Struct, error) {
+	s := &Struct{Name: name, Table: table}
+	for _, f := range st.Fields.List {
+		if f.Tag == nil || len(f.Names) != 1 {
+			continue
+		}
+		tagText, err := strconv.Unquote(f.Tag.Value)
+		if err != nil {
+			return nil, err
+		}
+		tag := reflect.StructTag(tagText)
+		col := tag.Get("column")
+		if col == "" {
+			col = tag.Get("col")
+		}
+		if col == "" {
+			col = tag.Get("db")
+		}
+		if col == "" {
+			continue
+		}
+
+		typ := bytes.Buffer{}
+		if err := printer.Fprint(&typ, fset, f.Type); err != nil {
+			return nil, err
+		}
+		def := strings.ToLower(tag.Get("sqlite") + " " + tag.Get("sqlite3") +
+			" " + tag.Get("mysql") + " " + tag.Get("postgre"))
+		s.Fields = append(s.Fields, Field{
+			Name: f.Names[0].Name, Column: col, Type: typ.String(),
+			IsPrimaryKey: strings.Contains(def, "primary key"),
+			IsUnique:     strings.Contains(def, "unique"),
+		})
+	}
+
+	if len(s.Fields) == 0 {
+		return nil, fmt.Errorf("struct %s doesn't have column fields", name)
+	}
+	return s, nil
+}
+
+// Helpers writes column constants and typed helpers of structs: for a struct
+// User of user table, UserTableName and UserCol<Field> constants are the
+// table and column names, UserColumns has a field of column name for every
+// column, and the Users variable has Insert, Find, All and FindBy<Field>
+// methods for primary key and unique columns, e.g.
+//
+//	user, err := Users.FindByUserid(ctx, db, "u1")
+//	users, err := Users.Find(ctx, tx, UserColNickname+"=?", "eschao")
+func Helpers(w io.Writer, pkg string, structs []Struct) error {
+	src := bytes.Buffer{}
+	src.WriteString("// Code generated by dbx helpers. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", pkg)
+	src.WriteString("import (\n\"context\"\n\n" +
+		"dbx \"github.com/eschao/go-dbx\"\n)\n\n")
+	for _, s := range structs {
+		if err := helperTemplate.Execute(&src, helperData{
+			Struct: s, Var: Plural(s.Name),
+		}); err != nil {
+			return err
+		}
+	}
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// SnakeName converts a Go name to snake case, e.g. UserLogin to user_login
+func SnakeName(name string) string {
+	runes := []rune(name)
+	s := []rune{}
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				s = append(s, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		s = append(s, r)
+	}
+	return string(s)
+}
+
+// Plural returns the plural of an English noun in simple rules
+func Plural(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, "s") || strings.HasSuffix(lower, "x") ||
+		strings.HasSuffix(lower, "z") || strings.HasSuffix(lower, "ch") ||
+		strings.HasSuffix(lower, "sh"):
+		return name + "es"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 &&
+		!strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+		return name[:len(name)-1] + "ies"
+	}
+	return name + "s"
+}
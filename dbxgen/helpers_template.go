@@ -0,0 +1,86 @@
+package dbxgen
+
+import (
+	"text/template"
+)
+
+type helperData struct {
+	Struct
+	Var string
+}
+
+var helperTemplate = template.Must(template.New("helpers").Parse(`
+// table and column names of {{.Table}} table
+const (
+	{{.Name}}TableName = "{{.Table}}"
+{{- $s := .}}
+{{- range .Fields}}
+	{{$s.Name}}Col{{.Name}} = "{{.Column}}"
+{{- end}}
+)
+
+// {{.Name}}Columns are column names of {{.Table}} table
+type {{.Name}}Columns struct {
+{{- range .Fields}}
+	{{.Name}} string
+{{- end}}
+}
+
+// {{.Name}}Table has typed helpers of {{.Table}} table
+type {{.Name}}Table struct {
+	Name string
+	Cols {{.Name}}Columns
+}
+
+// {{.Var}} has typed helpers of {{.Table}} table
+var {{.Var}} = {{.Name}}Table{
+	Name: {{.Name}}TableName,
+	Cols: {{.Name}}Columns{
+{{- range .Fields}}
+		{{.Name}}: {{$s.Name}}Col{{.Name}},
+{{- end}}
+	},
+}
+
+// T returns executor of {{.Table}} table with ctx
+func (this {{.Name}}Table) T(ctx context.Context, q dbx.Querier) *dbx.SQLExecutor {
+	return q.T(this.Name).WithContext(ctx)
+}
+
+// Insert inserts row to {{.Table}} table
+func (this {{.Name}}Table) Insert(ctx context.Context, q dbx.Querier, row *{{.Name}}) error {
+	_, err := this.T(ctx, q).Insert(row)
+	return err
+}
+
+// All selects all rows of {{.Table}} table
+func (this {{.Name}}Table) All(ctx context.Context, q dbx.Querier) ([]{{.Name}}, error) {
+	rows := []{{.Name}}{}
+	if err := this.T(ctx, q).SelectAll().All(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Find selects rows of {{.Table}} table by filter
+func (this {{.Name}}Table) Find(ctx context.Context, q dbx.Querier, where string, args ...interface{}) ([]{{.Name}}, error) {
+	rows := []{{.Name}}{}
+	if err := this.T(ctx, q).SelectAll().Filter(where, args...).All(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+{{- range .Fields}}
+{{- if or .IsPrimaryKey .IsUnique}}
+
+// FindBy{{.Name}} selects the row of {{$s.Table}} table by {{.Column}}
+func (this {{$s.Name}}Table) FindBy{{.Name}}(ctx context.Context, q dbx.Querier, v {{.Type}}) (*{{$s.Name}}, error) {
+	row := &{{$s.Name}}{}
+	if err := this.T(ctx, q).SelectAll().Filter(this.Cols.{{.Name}}+"=?", v).One(row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+{{- end}}
+{{- end}}
+`))
@@ -0,0 +1,99 @@
+package dbxgen
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testModels = `package models
+
+type User struct {
+	Id       int64  ` + "`db:\"id\" sqlite:\"INTEGER PRIMARY KEY AUTOINCREMENT\"`" + `
+	Userid   string ` + "`db:\"userid\" sqlite:\"TEXT NOT NULL UNIQUE\"`" + `
+	Nickname string ` + "`column:\"nickname\" sqlite:\"TEXT\"`" + `
+	Ignored  string
+}
+
+type UserLogin struct {
+	Id     int64  ` + "`col:\"id\" mysql:\"int PRIMARY KEY AUTO_INCREMENT\"`" + `
+	Userid string ` + "`col:\"userid\" mysql:\"varchar(32)\"`" + `
+}
+`
+
+func TestParseStructs(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "models.go"),
+		[]byte(testModels), 0644))
+
+	pkg, structs, err := ParseStructs(dir, []string{"User", "UserLogin"},
+		map[string]string{"User": "users"})
+	assert.Nil(err)
+	assert.Equal("models", pkg)
+	assert.Equal(2, len(structs))
+	assert.Equal("users", structs[0].Table)
+	assert.Equal([]Field{
+		{Name: "Id", Column: "id", Type: "int64", IsPrimaryKey: true},
+		{Name: "Userid", Column: "userid", Type: "string", IsUnique: true},
+		{Name: "Nickname", Column: "nickname", Type: "string"},
+	}, structs[0].Fields)
+	assert.Equal("user_login", structs[1].Table)
+	assert.Equal(2, len(structs[1].Fields))
+
+	_, _, err = ParseStructs(dir, []string{"Missing"}, nil)
+	assert.NotNil(err)
+}
+
+func TestHelpers(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "models.go"),
+		[]byte(testModels), 0644))
+	pkg, structs, err := ParseStructs(dir, []string{"User", "UserLogin"}, nil)
+	assert.Nil(err)
+
+	buf := bytes.Buffer{}
+	assert.Nil(Helpers(&buf, pkg, structs))
+	src := buf.String()
+	_, err = parser.ParseFile(token.NewFileSet(), "", src, 0)
+	assert.Nil(err)
+
+	assert.True(strings.HasPrefix(src, "// Code generated by dbx helpers."))
+	assert.Contains(src, "package models")
+	assert.Contains(src, "var Users = UserTable{")
+	assert.Contains(src, "var UserLogins = UserLoginTable{")
+	assert.Contains(src, "const (\n\tUserTableName   = \"user\"\n"+
+		"\tUserColId       = \"id\"\n")
+	assert.Contains(src, "UserColNickname = \"nickname\"")
+	assert.Contains(src, "UserLoginColUserid = \"userid\"")
+	assert.Contains(src, "Nickname: UserColNickname,")
+	assert.Contains(src, "func (this UserTable) FindById(ctx context.Context, "+
+		"q dbx.Querier, v int64) (*User, error) {")
+	assert.Contains(src, "func (this UserTable) FindByUserid(ctx context.Context, "+
+		"q dbx.Querier, v string) (*User, error) {")
+	assert.NotContains(src, "FindByNickname")
+	assert.NotContains(src, "Ignored")
+	assert.NotContains(src, "UserLoginTable) FindByUserid")
+}
+
+func TestNames(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("user_login", SnakeName("UserLogin"))
+	assert.Equal("user_id", SnakeName("UserID"))
+	assert.Equal("http_server", SnakeName("HTTPServer"))
+	assert.Equal("users", Plural("user"))
+	assert.Equal("Addresses", Plural("Address"))
+	assert.Equal("Categories", Plural("Category"))
+	assert.Equal("Keys", Plural("Key"))
+	assert.Equal("Boxes", Plural("Box"))
+}
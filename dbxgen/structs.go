@@ -0,0 +1,207 @@
+// Package dbxgen generates Go code for dbx: structs of existing database
+// tables and typed helpers of registered table structs. It's used by the
+// dbx command
+package dbxgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+	"strings"
+
+	dbx "github.com/eschao/go-dbx"
+)
+
+// StructOptions defines options of Structs
+type StructOptions struct {
+	// Package is the package name of generated file
+	Package string
+	// Driver is the driver of tables, it decides the tag of column
+	// definitions
+	Driver string
+	// NullTypes uses sql.Null* types for nullable columns instead of
+	// pointers
+	NullTypes bool
+	// RegisterFunc is the name of generated function registering all tables,
+	// default is RegisterTables
+	RegisterFunc string
+}
+
+// Structs writes Go structs of tables whose tags are accepted by
+// dbx.Table.Parse, and a function registering them
+func Structs(w io.Writer, tables []dbx.TableSchema, opts *StructOptions) error {
+	if opts.Package == "" {
+		return fmt.Errorf("no package name")
+	}
+	tag := ""
+	switch opts.Driver {
+	case dbx.DRIVER_SQLITE3:
+		tag = "sqlite"
+	case dbx.DRIVER_MYSQL:
+		tag = "mysql"
+	default:
+		return fmt.Errorf("unsupportted driver %s", opts.Driver)
+	}
+	register := opts.RegisterFunc
+	if register == "" {
+		register = "RegisterTables"
+	}
+
+	body := bytes.Buffer{}
+	usesSQL := false
+	for _, t := range tables {
+		fmt.Fprintf(&body, "// %s is a row of %s table\n", GoName(t.Name), t.Name)
+		fmt.Fprintf(&body, "type %s struct {\n", GoName(t.Name))
+		for _, c := range t.Columns {
+			typ := goType(c, opts.Driver, opts.NullTypes)
+			if strings.HasPrefix(typ, "sql.") {
+				usesSQL = true
+			}
+			// rowid aliases are assigned by SQLite without AUTOINCREMENT
+			opt := ""
+			if c.IsRowidAlias && opts.Driver == dbx.DRIVER_SQLITE3 {
+				opt = " dbx:\"autoIncrement\""
+			}
+			fmt.Fprintf(&body, "%s %s `json:%s db:%s %s:%s%s`\n", GoName(c.Name),
+				typ, strconv.Quote(c.Name), strconv.Quote(c.Name), tag,
+				strconv.Quote(Definition(c, opts.Driver)), opt)
+		}
+		body.WriteString("}\n\n")
+	}
+
+	fmt.Fprintf(&body, "// %s registers tables of the generated structs\n",
+		register)
+	fmt.Fprintf(&body, "func %s(db *dbx.Database) error {\n", register)
+	for _, t := range tables {
+		fmt.Fprintf(&body, "if err := db.RegisterTable(%s, %s{}); err != nil {\n"+
+			"return err\n}\n", strconv.Quote(t.Name), GoName(t.Name))
+	}
+	body.WriteString("return nil\n}\n")
+
+	src := bytes.Buffer{}
+	src.WriteString("// Code generated by dbx gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\nimport (\n", opts.Package)
+	if usesSQL {
+		src.WriteString("\"database/sql\"\n\n")
+	}
+	src.WriteString("dbx \"github.com/eschao/go-dbx\"\n)\n\n")
+	src.Write(body.Bytes())
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// Definition returns the column definition of driver, e.g.
+// "int NOT NULL PRIMARY KEY AUTO_INCREMENT"
+func Definition(c dbx.ColumnSchema, driver string) string {
+	def := []string{c.Type}
+	if driver == dbx.DRIVER_SQLITE3 {
+		if c.IsPrimaryKey {
+			def = append(def, "PRIMARY KEY")
+			if c.IsAutoIncrement && !c.IsRowidAlias {
+				def = append(def, "AUTOINCREMENT")
+			}
+		}
+		if c.IsUnique {
+			def = append(def, "UNIQUE")
+		}
+		if !c.Nullable && !c.IsPrimaryKey {
+			def = append(def, "NOT NULL")
+		}
+		if c.Default != nil {
+			def = append(def, "DEFAULT "+*c.Default)
+		}
+		return strings.Join(def, " ")
+	}
+
+	if !c.Nullable {
+		def = append(def, "NOT NULL")
+	}
+	if c.Default != nil {
+		def = append(def, "DEFAULT "+mysqlDefault(c))
+	}
+	if c.IsPrimaryKey {
+		def = append(def, "PRIMARY KEY")
+	}
+	if c.IsAutoIncrement {
+		def = append(def, "AUTO_INCREMENT")
+	}
+	if c.IsUnique {
+		def = append(def, "UNIQUE")
+	}
+	return strings.Join(def, " ")
+}
+
+// mysqlDefault quotes the unquoted default value of information_schema
+// unless it's a number or an expression
+func mysqlDefault(c dbx.ColumnSchema) string {
+	d := *c.Default
+	if _, err := strconv.ParseFloat(d, 64); err == nil {
+		return d
+	}
+	u := strings.ToUpper(d)
+	if u == "NULL" || strings.HasPrefix(u, "CURRENT_TIMESTAMP") ||
+		strings.HasPrefix(d, "(") || strings.HasPrefix(d, "'") {
+		return d
+	}
+	return "'" + strings.Replace(d, "'", "''", -1) + "'"
+}
+
+// goType returns the Go type of column
+func goType(c dbx.ColumnSchema, driver string, nullTypes bool) string {
+	t := strings.ToLower(c.Type)
+	base, null := "string", "sql.NullString"
+	switch {
+	case strings.HasPrefix(t, "tinyint(1)") || strings.HasPrefix(t, "bool"):
+		base, null = "bool", "sql.NullBool"
+	case strings.Contains(t, "int"):
+		base, null = "int64", "sql.NullInt64"
+	case strings.Contains(t, "real") || strings.Contains(t, "floa") ||
+		strings.Contains(t, "doub") || strings.HasPrefix(t, "decimal") ||
+		strings.HasPrefix(t, "numeric"):
+		base, null = "float64", "sql.NullFloat64"
+	case strings.Contains(t, "blob") || strings.Contains(t, "binary") ||
+		(t == "" && driver == dbx.DRIVER_SQLITE3):
+		return "[]byte"
+	}
+
+	if !c.Nullable {
+		return base
+	}
+	if nullTypes {
+		return null
+	}
+	return "*" + base
+}
+
+// common initialisms in Go names
+var initialisms = map[string]bool{
+	"API": true, "HTML": true, "HTTP": true, "ID": true, "IP": true,
+	"JSON": true, "SQL": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// GoName converts a snake case name of table or column to an exported Go
+// name, e.g. user_id to UserID
+func GoName(name string) string {
+	s := ""
+	for _, w := range strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+			r >= '0' && r <= '9')
+	}) {
+		if u := strings.ToUpper(w); initialisms[u] {
+			s += u
+		} else {
+			s += strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		s = "T" + s
+	}
+	return s
+}
@@ -0,0 +1,189 @@
+package dbxgen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	dbx "github.com/eschao/go-dbx"
+	"github.com/stretchr/testify/assert"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+var testSchemas = []dbx.TableSchema{
+	{Name: "user_login", Columns: []dbx.ColumnSchema{
+		{Name: "id", Type: "INTEGER", IsPrimaryKey: true, IsAutoIncrement: true},
+		{Name: "user_id", Type: "TEXT", IsUnique: true},
+		{Name: "nickname", Type: "TEXT", Nullable: true, Default: strPtr("''")},
+		{Name: "last_ip", Type: "INTEGER", Nullable: true},
+		{Name: "score", Type: "REAL"},
+	}},
+}
+
+func TestStructs(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.Buffer{}
+	assert.Nil(Structs(&buf, testSchemas, &StructOptions{
+		Package: "models", Driver: dbx.DRIVER_SQLITE3,
+	}))
+	src := buf.String()
+	_, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	assert.Nil(err)
+
+	assert.True(strings.HasPrefix(src, "// Code generated by dbx gen."))
+	assert.Contains(src, "type UserLogin struct {")
+	assert.Contains(src, "ID       int64   `json:\"id\" db:\"id\" "+
+		"sqlite:\"INTEGER PRIMARY KEY AUTOINCREMENT\"`")
+	assert.Contains(src, "UserID   string  `json:\"user_id\" db:\"user_id\" "+
+		"sqlite:\"TEXT UNIQUE NOT NULL\"`")
+	assert.Contains(src, "Nickname *string `json:\"nickname\" "+
+		"db:\"nickname\" sqlite:\"TEXT DEFAULT ''\"`")
+	assert.Contains(src, "LastIP   *int64")
+	assert.Contains(src, "Score    float64")
+	assert.Contains(src, "func RegisterTables(db *dbx.Database) error {")
+	assert.Contains(src, "db.RegisterTable(\"user_login\", UserLogin{})")
+	assert.NotContains(src, "database/sql")
+
+	// sql.Null* types
+	buf.Reset()
+	assert.Nil(Structs(&buf, testSchemas, &StructOptions{
+		Package: "models", Driver: dbx.DRIVER_SQLITE3, NullTypes: true,
+		RegisterFunc: "Register",
+	}))
+	src = buf.String()
+	assert.Contains(src, "\"database/sql\"")
+	assert.Contains(src, "LastIP   sql.NullInt64")
+	assert.Contains(src, "func Register(db *dbx.Database) error {")
+
+	assert.NotNil(Structs(&buf, testSchemas, &StructOptions{
+		Package: "models", Driver: "unknown",
+	}))
+}
+
+// structOf builds the type of struct name in generated source src
+func structOf(t *testing.T, src, name string) reflect.Type {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	assert.Nil(t, err)
+	types := map[string]reflect.Type{
+		"int64": reflect.TypeOf(int64(0)), "*int64": reflect.TypeOf((*int64)(nil)),
+		"string": reflect.TypeOf(""), "*string": reflect.TypeOf((*string)(nil)),
+		"float64": reflect.TypeOf(float64(0)),
+	}
+
+	fields := []reflect.StructField{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok || spec.Name.Name != name {
+			return true
+		}
+		for _, field := range spec.Type.(*ast.StructType).Fields.List {
+			typ, ok := types[gotypes.ExprString(field.Type)]
+			assert.True(t, ok, gotypes.ExprString(field.Type))
+			tag, err := strconv.Unquote(field.Tag.Value)
+			assert.Nil(t, err)
+			fields = append(fields, reflect.StructField{
+				Name: field.Names[0].Name, Type: typ, Tag: reflect.StructTag(tag),
+			})
+		}
+		return false
+	})
+	return reflect.StructOf(fields)
+}
+
+func TestStructsRegistered(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, driver := range []string{dbx.DRIVER_MYSQL, dbx.DRIVER_SQLITE3} {
+		buf := bytes.Buffer{}
+		assert.Nil(Structs(&buf, testSchemas, &StructOptions{
+			Package: "models", Driver: driver,
+		}))
+		row := reflect.New(structOf(t, buf.String(), "UserLogin")).Elem()
+
+		db := dbx.NewDatabase()
+		assert.Nil(db.RegisterTable("user_login", row.Interface()), driver)
+		table, err := db.GetTableSchema("user_login")
+		assert.Nil(err)
+		assert.Equal("id", table.PrimaryKey(), driver)
+		assert.True(table.Columns["id"].IsAutoIncrement, driver)
+		assert.False(table.Columns["user_id"].IsPrimaryKey, driver)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	assert := assert.New(t)
+
+	c := dbx.ColumnSchema{Name: "id", Type: "int", IsPrimaryKey: true,
+		IsAutoIncrement: true}
+	assert.Equal("int NOT NULL PRIMARY KEY AUTO_INCREMENT",
+		Definition(c, dbx.DRIVER_MYSQL))
+	assert.Equal("int PRIMARY KEY AUTOINCREMENT",
+		Definition(c, dbx.DRIVER_SQLITE3))
+	c = dbx.ColumnSchema{Name: "id", Type: "INTEGER", IsPrimaryKey: true,
+		IsAutoIncrement: true, IsRowidAlias: true}
+	assert.Equal("INTEGER PRIMARY KEY", Definition(c, dbx.DRIVER_SQLITE3))
+
+	c = dbx.ColumnSchema{Name: "name", Type: "varchar(32)",
+		Default: strPtr("it's"), IsUnique: true}
+	assert.Equal("varchar(32) NOT NULL DEFAULT 'it''s' UNIQUE",
+		Definition(c, dbx.DRIVER_MYSQL))
+	c.Default = strPtr("0")
+	assert.Equal("varchar(32) NOT NULL DEFAULT 0 UNIQUE",
+		Definition(c, dbx.DRIVER_MYSQL))
+	c = dbx.ColumnSchema{Name: "t", Type: "datetime", Nullable: true,
+		Default: strPtr("CURRENT_TIMESTAMP")}
+	assert.Equal("datetime DEFAULT CURRENT_TIMESTAMP",
+		Definition(c, dbx.DRIVER_MYSQL))
+}
+
+func TestGoName(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("User", GoName("user"))
+	assert.Equal("UserLogin", GoName("user_login"))
+	assert.Equal("UserID", GoName("user_id"))
+	assert.Equal("LastIP", GoName("last_ip"))
+	assert.Equal("OauthURL", GoName("oauth_url"))
+	assert.Equal("T2fa", GoName("2fa"))
+}
+
+func TestStructsFromDatabase(t *testing.T) {
+	assert := assert.New(t)
+
+	db := dbx.NewDatabase()
+	assert.Nil(db.OpenSQLite(":memory:"))
+	defer db.Close()
+	db.DB().SetMaxOpenConns(1)
+	_, err := db.DB().Exec("CREATE TABLE user(id INTEGER PRIMARY KEY, " +
+		"userid TEXT NOT NULL UNIQUE, age INTEGER)")
+	assert.Nil(err)
+	_, err = db.DB().Exec("CREATE TABLE login(id INTEGER PRIMARY KEY " +
+		"AUTOINCREMENT, ip INTEGER)")
+	assert.Nil(err)
+
+	schemas, err := db.Introspect()
+	assert.Nil(err)
+	buf := bytes.Buffer{}
+	assert.Nil(Structs(&buf, schemas, &StructOptions{
+		Package: "models", Driver: dbx.DRIVER_SQLITE3,
+	}))
+	// AUTOINCREMENT is only generated if table has it, rowid aliases are
+	// marked by autoIncrement option
+	assert.Contains(buf.String(), "ID     int64  `json:\"id\" db:\"id\" "+
+		"sqlite:\"INTEGER PRIMARY KEY\" dbx:\"autoIncrement\"`")
+	assert.Contains(buf.String(), "ID int64  `json:\"id\" db:\"id\" "+
+		"sqlite:\"INTEGER PRIMARY KEY AUTOINCREMENT\"`")
+	assert.Contains(buf.String(), "Userid string `json:\"userid\" "+
+		"db:\"userid\" sqlite:\"TEXT UNIQUE NOT NULL\"`")
+	assert.Contains(buf.String(), "Age    *int64")
+}
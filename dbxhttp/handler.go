@@ -0,0 +1,535 @@
+// Package dbxhttp exposes registered dbx tables as REST CRUD endpoints:
+//
+//	http.Handle("/users/", http.StripPrefix("/users",
+//		dbxhttp.Handler(db, "user", &dbxhttp.Options{
+//			Query: dbx.QueryRules{
+//				Filters:  map[string][]string{"userid": nil},
+//				Sortable: []string{"nickname"},
+//			},
+//		})))
+//
+// The handler serves, relative to its root, where list parameters are parsed
+// by SQLSelector.FromQuery:
+//
+//	GET    /      list rows: ?userid=u1&sort=-id&limit=20&page=2
+//	POST   /      create a row from form or JSON body
+//	GET    /{id}  get the row of primary key id
+//	PUT    /{id}  update columns given by form or JSON body, PATCH as well
+//	DELETE /{id}  delete the row
+package dbxhttp
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	dbx "github.com/eschao/go-dbx"
+)
+
+// actions passed to Options.Authorize
+const (
+	ACTION_LIST   = "list"
+	ACTION_GET    = "get"
+	ACTION_CREATE = "create"
+	ACTION_UPDATE = "update"
+	ACTION_DELETE = "delete"
+)
+
+const (
+	defaultLimit        = 20
+	defaultMaxLimit     = 100
+	defaultMaxBodyBytes = 1 << 20 // 1 MB
+)
+
+// Options defines options of Handler
+type Options struct {
+	// Query whitelists filters and sorting of list parameters parsed by
+	// SQLSelector.FromQuery. DefaultLimit is 20 and MaxLimit is 100 if they
+	// are 0. Rows can always be sorted by primary key, which breaks ties of
+	// other sorting
+	Query dbx.QueryRules
+	// Writable are columns which can be set by create and update, default is
+	// all columns. Version, soft delete, timestamp and auto increment primary
+	// key columns are never set from requests, and primary key can't be
+	// updated. Values of other columns in requests are ignored
+	Writable []string
+	// Hidden are columns left out of responses, e.g. password
+	Hidden []string
+	// MaxBodyBytes is the max size of request bodies, default is 1 MB. A
+	// larger body is responded with 413 status
+	MaxBodyBytes int64
+	// Scope limits rows of list, as Authorize can't check them one by one.
+	// Filter of the returned selector is ANDed with list parameters:
+	//
+	//	Scope: func(r *http.Request, s *dbx.SQLSelector) *dbx.SQLSelector {
+	//		return s.Filter("userid=?", r.Header.Get("User"))
+	//	}
+	Scope func(r *http.Request, s *dbx.SQLSelector) *dbx.SQLSelector
+	// Authorize is called before every action, row is nil for list, the
+	// bound row for create, and the existing row for get, update and delete.
+	// Update calls it again with the bound row before saving it, so values of
+	// request are authorized as well. An error is responded with 403 status
+	// unless it's an *Error, so is an *Error without Status
+	Authorize func(r *http.Request, action string, row interface{}) error
+	// ErrorLog logs errors responded with 500 status whose details aren't
+	// sent to clients, the standard logger is used if it's nil
+	ErrorLog *log.Logger
+}
+
+// Error is an error responded with its status
+type Error struct {
+	Status  int
+	Message string
+	// Fields are conversion errors of request fields
+	Fields map[string]string
+}
+
+func (this *Error) Error() string {
+	return this.Message
+}
+
+// ListResult is the response of list
+type ListResult struct {
+	Items interface{} `json:"items"`
+	Total int         `json:"total"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
+}
+
+type handler struct {
+	db    *dbx.Database
+	table dbx.Table
+	pk    dbx.Column
+	opts  Options
+	// struct type of responses without hidden columns and indexes of its
+	// fields in row, nil if no column is hidden
+	respType   reflect.Type
+	respFields []int
+}
+
+// Handler returns an http.Handler of CRUD endpoints of the registered table
+// which must have a primary key. Rows are bound from requests by
+// Table.BindRequest and responded as JSON. It panics if the table can't be
+// served, as http.Handle does for invalid patterns
+func Handler(db *dbx.Database, table string, opts *Options) http.Handler {
+	t, err := db.GetTableSchema(table)
+	if err == nil && t.Name == "" {
+		err = fmt.Errorf("%s table is not registered", table)
+	}
+	if err != nil {
+		panic("dbxhttp: " + err.Error())
+	}
+	pk := t.PrimaryKey()
+	if pk == "" {
+		panic("dbxhttp: " + table + " table has no primary key")
+	}
+
+	h := &handler{db: db, table: t, pk: t.Columns[pk]}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.MaxBodyBytes <= 0 {
+		h.opts.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	rules := &h.opts.Query
+	if rules.DefaultLimit <= 0 {
+		rules.DefaultLimit = defaultLimit
+	}
+	if rules.MaxLimit <= 0 {
+		rules.MaxLimit = defaultMaxLimit
+	}
+	rules.Sortable = append([]string{pk}, rules.Sortable...)
+	filters := make([]string, 0, len(rules.Filters))
+	for c := range rules.Filters {
+		filters = append(filters, c)
+	}
+	for _, cols := range [][]string{rules.Sortable, filters,
+		h.opts.Writable, h.opts.Hidden} {
+		for _, c := range cols {
+			if _, ok := t.Columns[c]; !ok {
+				panic(fmt.Sprintf("dbxhttp: %s table has no column %s", table, c))
+			}
+		}
+	}
+	h.initResponse()
+	return h
+}
+
+// initResponse makes the struct type of responses without hidden columns
+func (this *handler) initResponse() {
+	if len(this.opts.Hidden) == 0 {
+		return
+	}
+	hidden := map[int]bool{}
+	for _, c := range this.opts.Hidden {
+		hidden[this.table.Columns[c].Index] = true
+	}
+
+	rowType := this.table.RowType()
+	fields := []reflect.StructField{}
+	for i := 0; i < rowType.NumField(); i++ {
+		f := rowType.Field(i)
+		if f.IsExported() && !hidden[i] {
+			fields = append(fields, reflect.StructField{
+				Name: f.Name, Type: f.Type, Tag: f.Tag, Anonymous: f.Anonymous,
+			})
+			this.respFields = append(this.respFields, i)
+		}
+	}
+	this.respType = reflect.StructOf(fields)
+}
+
+// response returns row, a pointer to table row, without hidden columns
+func (this *handler) response(row interface{}) interface{} {
+	if this.respType == nil {
+		return row
+	}
+	rowVal := reflect.ValueOf(row).Elem()
+	resp := reflect.New(this.respType)
+	for i, j := range this.respFields {
+		resp.Elem().Field(i).Set(rowVal.Field(j))
+	}
+	return resp.Interface()
+}
+
+// writable checks if column c can be set from requests, the primary key
+// can only be set by create if it's not auto increment
+func (this *handler) writable(c dbx.Column, create bool) bool {
+	if c.IsPrimaryKey && (!create || c.IsAutoIncrement) || c.IsVersion ||
+		c.IsSoftDelete || c.IsAutoCreateTime || c.IsAutoUpdateTime {
+		return false
+	}
+	return len(this.opts.Writable) == 0 || contains(this.opts.Writable, c.Name)
+}
+
+// bind sets writable columns of row from request and returns them
+func (this *handler) bind(r *http.Request, row interface{}, create bool) (
+	[]string, error) {
+	bound := reflect.New(this.table.RowType())
+	cols, err := this.table.BindRequest(r, bound.Interface())
+	if err != nil {
+		return nil, bindError(err)
+	}
+
+	rowVal := reflect.ValueOf(row).Elem()
+	writes := []string{}
+	for _, name := range cols {
+		c := this.table.Columns[name]
+		if this.writable(c, create) {
+			rowVal.Field(c.Index).Set(bound.Elem().Field(c.Index))
+			writes = append(writes, name)
+		}
+	}
+	return writes, nil
+}
+
+func (this *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, this.opts.MaxBodyBytes)
+	}
+	path := strings.Trim(r.URL.Path, "/")
+	var status int
+	var resp interface{}
+	var err error
+	switch {
+	case strings.Contains(path, "/"):
+		err = &Error{Status: http.StatusNotFound, Message: "not found"}
+	case path == "" && r.Method == http.MethodGet:
+		status = http.StatusOK
+		resp, err = this.list(r)
+	case path == "" && r.Method == http.MethodPost:
+		status = http.StatusCreated
+		resp, err = this.create(r)
+	case path == "":
+		w.Header().Set("Allow", "GET, POST")
+		err = &Error{Status: http.StatusMethodNotAllowed,
+			Message: "method not allowed"}
+	case r.Method == http.MethodGet:
+		status = http.StatusOK
+		resp, err = this.get(r, path)
+	case r.Method == http.MethodPut || r.Method == http.MethodPatch:
+		status = http.StatusOK
+		resp, err = this.update(r, path)
+	case r.Method == http.MethodDelete:
+		status = http.StatusNoContent
+		err = this.delete(r, path)
+	default:
+		w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+		err = &Error{Status: http.StatusMethodNotAllowed,
+			Message: "method not allowed"}
+	}
+
+	if err != nil {
+		this.writeError(w, r, err)
+		return
+	}
+	if resp == nil {
+		w.WriteHeader(status)
+		return
+	}
+	writeJSON(w, status, resp)
+}
+
+// list selects a page of rows by query parameters
+func (this *handler) list(r *http.Request) (interface{}, error) {
+	if err := this.authorize(r, ACTION_LIST, nil); err != nil {
+		return nil, err
+	}
+
+	// pages are stable only in a total order, primary key breaks ties
+	values := r.URL.Query()
+	sort := []string{}
+	if s := values.Get(dbx.QUERY_SORT); s != "" {
+		sort = strings.Split(s, ",")
+	}
+	hasPK := false
+	for _, c := range sort {
+		hasPK = hasPK || strings.TrimPrefix(c, "-") == this.pk.Name
+	}
+	if !hasPK {
+		values.Set(dbx.QUERY_SORT, strings.Join(append(sort, this.pk.Name), ","))
+	}
+
+	e := this.db.T(this.table.Name).WithContext(r.Context())
+	s := e.SelectAll()
+	if this.opts.Scope != nil {
+		s = this.opts.Scope(r, s)
+	}
+	s.FromQuery(values, &this.opts.Query)
+	// errors of FromQuery are kept by selector until it's run
+	if _, _, err := s.ToSQL(); err != nil {
+		return nil, badRequest("%v", err)
+	}
+	rows := reflect.New(reflect.SliceOf(this.table.RowType()))
+	if err := s.All(rows.Interface()); err != nil {
+		return nil, err
+	}
+	total, err := s.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	// parameters are valid as they're accepted by FromQuery
+	page, _ := intParam(values.Get(dbx.QUERY_PAGE), 1)
+	limit, _ := intParam(values.Get(dbx.QUERY_LIMIT),
+		this.opts.Query.DefaultLimit)
+	items := make([]interface{}, rows.Elem().Len())
+	for i := range items {
+		items[i] = this.response(rows.Elem().Index(i).Addr().Interface())
+	}
+	return &ListResult{
+		Items: items, Total: total, Page: page, Limit: limit,
+	}, nil
+}
+
+// get selects the row of primary key id
+func (this *handler) get(r *http.Request, id string) (interface{}, error) {
+	row, err := this.find(r, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := this.authorize(r, ACTION_GET, row); err != nil {
+		return nil, err
+	}
+	return this.response(row), nil
+}
+
+// create inserts a row of writable columns bound from request
+func (this *handler) create(r *http.Request) (interface{}, error) {
+	row := reflect.New(this.table.RowType())
+	if _, err := this.bind(r, row.Interface(), true); err != nil {
+		return nil, err
+	}
+	if err := this.authorize(r, ACTION_CREATE, row.Interface()); err != nil {
+		return nil, err
+	}
+
+	e := this.db.T(this.table.Name).WithContext(r.Context())
+	rs, err := e.Insert(row.Interface())
+	if err != nil {
+		return nil, err
+	}
+	if this.pk.IsAutoIncrement {
+		field := row.Elem().Field(this.pk.Index)
+		if id, err := rs.LastInsertId(); err == nil && field.CanInt() {
+			field.SetInt(id)
+		}
+	}
+	return this.response(row.Interface()), nil
+}
+
+// update updates writable columns of the row bound from request
+func (this *handler) update(r *http.Request, id string) (interface{}, error) {
+	row, err := this.find(r, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := this.authorize(r, ACTION_UPDATE, row); err != nil {
+		return nil, err
+	}
+
+	updates, err := this.bind(r, row, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(updates) == 0 {
+		return nil, badRequest("no columns to update")
+	}
+	if err := this.authorize(r, ACTION_UPDATE, row); err != nil {
+		return nil, err
+	}
+
+	key := reflect.ValueOf(row).Elem().Field(this.pk.Index).Interface()
+	_, err = this.db.T(this.table.Name).WithContext(r.Context()).
+		Update(this.pk.Name+"=?", key).Set(updates...).Value(row)
+	if err != nil {
+		return nil, err
+	}
+	return this.response(row), nil
+}
+
+// delete deletes the row of primary key id
+func (this *handler) delete(r *http.Request, id string) error {
+	row, err := this.find(r, id)
+	if err != nil {
+		return err
+	}
+	if err := this.authorize(r, ACTION_DELETE, row); err != nil {
+		return err
+	}
+	return this.db.T(this.table.Name).WithContext(r.Context()).DeleteRow(row)
+}
+
+// find selects the row of primary key id, it returns a 404 error if the row
+// doesn't exist
+func (this *handler) find(r *http.Request, id string) (interface{}, error) {
+	notFound := &Error{Status: http.StatusNotFound, Message: "not found"}
+	key := reflect.New(this.table.RowType().Field(this.pk.Index).Type).Elem()
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		v, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, notFound
+		}
+		key.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		v, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return nil, notFound
+		}
+		key.SetUint(v)
+	case reflect.String:
+		key.SetString(id)
+	default:
+		return nil, fmt.Errorf("unsupported primary key type %s", key.Type())
+	}
+
+	row := reflect.New(this.table.RowType()).Interface()
+	err := this.db.T(this.table.Name).WithContext(r.Context()).SelectAll().
+		Filter(this.pk.Name+"=?", key.Interface()).One(row)
+	if err == sql.ErrNoRows {
+		return nil, notFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+func (this *handler) authorize(r *http.Request, action string,
+	row interface{}) error {
+	if this.opts.Authorize == nil {
+		return nil
+	}
+	err := this.opts.Authorize(r, action, row)
+	if err == nil {
+		return nil
+	}
+	if e := (*Error)(nil); errors.As(err, &e) {
+		if e.Status == 0 {
+			return &Error{Status: http.StatusForbidden, Message: e.Message,
+				Fields: e.Fields}
+		}
+		return e
+	}
+	return &Error{Status: http.StatusForbidden, Message: err.Error()}
+}
+
+func badRequest(format string, args ...interface{}) *Error {
+	return &Error{Status: http.StatusBadRequest,
+		Message: fmt.Sprintf(format, args...)}
+}
+
+// bindError converts error of BindRequest to a 400 error with field errors,
+// or a 413 error if the body is too large
+func bindError(err error) *Error {
+	if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+		return &Error{Status: http.StatusRequestEntityTooLarge,
+			Message: "request body too large"}
+	}
+	e := badRequest("%v", err)
+	if bindErr := (*dbx.BindError)(nil); errors.As(err, &bindErr) {
+		e.Message = "invalid request values"
+		e.Fields = map[string]string{}
+		for _, f := range bindErr.Errors {
+			e.Fields[f.Key] = f.Err.Error()
+		}
+	}
+	return e
+}
+
+// writeError responds err with its status, errors other than *Error and
+// stale rows are logged and responded as 500 without details
+func (this *handler) writeError(w http.ResponseWriter, r *http.Request,
+	err error) {
+	e := (*Error)(nil)
+	stale := (*dbx.ErrStaleObject)(nil)
+	switch {
+	case errors.As(err, &e) && e.Status != 0:
+	case errors.As(err, &stale):
+		e = &Error{Status: http.StatusConflict, Message: stale.Error()}
+	default:
+		logf := log.Printf
+		if this.opts.ErrorLog != nil {
+			logf = this.opts.ErrorLog.Printf
+		}
+		logf("dbxhttp: %s %s: %v", r.Method, r.URL.Path, err)
+		e = &Error{Status: http.StatusInternalServerError,
+			Message: "internal server error"}
+	}
+	body := map[string]interface{}{"error": e.Message}
+	if len(e.Fields) > 0 {
+		body["fields"] = e.Fields
+	}
+	writeJSON(w, e.Status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func intParam(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
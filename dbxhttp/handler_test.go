@@ -0,0 +1,432 @@
+package dbxhttp
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	dbx "github.com/eschao/go-dbx"
+	"github.com/eschao/go-dbx/dbxtest"
+	"github.com/stretchr/testify/assert"
+)
+
+type User struct {
+	Id       int64  `json:"id"       db:"id"       sqlite:"INTEGER PRIMARY KEY AUTOINCREMENT"`
+	Userid   string `json:"userid"   db:"userid"   sqlite:"TEXT NOT NULL"`
+	Nickname string `json:"nickname" db:"nickname" sqlite:"TEXT" form:"nick"`
+	Age      int    `json:"age"      db:"age"      sqlite:"INTEGER"`
+}
+
+func newServer(t *testing.T, opts *Options) (*dbx.Database, *httptest.Server) {
+	dbx.SetLogger(nil)
+	db := dbxtest.Open(t, dbxtest.Table{Name: "user", Row: User{}})
+	srv := httptest.NewServer(http.StripPrefix("/users",
+		Handler(db, "user", opts)))
+	t.Cleanup(srv.Close)
+	return db, srv
+}
+
+func do(t *testing.T, method, url, contentType, body string,
+	out interface{}) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.Nil(t, err)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	if out != nil {
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+func TestHandlerCRUD(t *testing.T) {
+	assert := assert.New(t)
+	_, srv := newServer(t, nil)
+	base := srv.URL + "/users"
+
+	// create from JSON and form
+	u := User{}
+	assert.Equal(http.StatusCreated, do(t, "POST", base, "application/json",
+		`{"userid": "u1", "nickname": "eschao", "age": 30}`, &u))
+	assert.Equal(User{Id: 1, Userid: "u1", Nickname: "eschao", Age: 30}, u)
+	form := url.Values{"userid": {"u2"}, "nick": {"chao"}, "age": {"20"}}
+	assert.Equal(http.StatusCreated, do(t, "POST", base,
+		"application/x-www-form-urlencoded", form.Encode(), &u))
+	assert.Equal(User{Id: 2, Userid: "u2", Nickname: "chao", Age: 20}, u)
+
+	// get
+	assert.Equal(http.StatusOK, do(t, "GET", base+"/1", "", "", &u))
+	assert.Equal("eschao", u.Nickname)
+	errResp := map[string]interface{}{}
+	assert.Equal(http.StatusNotFound, do(t, "GET", base+"/3", "", "", &errResp))
+	assert.Equal("not found", errResp["error"])
+	assert.Equal(http.StatusNotFound, do(t, "GET", base+"/x", "", "", nil))
+	assert.Equal(http.StatusNotFound, do(t, "GET", base+"/1/2", "", "", nil))
+
+	// partial update, primary key can't be changed
+	assert.Equal(http.StatusOK, do(t, "PATCH", base+"/1", "application/json",
+		`{"id": 9, "age": 31}`, &u))
+	assert.Equal(User{Id: 1, Userid: "u1", Nickname: "eschao", Age: 31}, u)
+	assert.Equal(http.StatusOK, do(t, "GET", base+"/1", "", "", &u))
+	assert.Equal(31, u.Age)
+
+	errResp = map[string]interface{}{}
+	assert.Equal(http.StatusBadRequest, do(t, "PUT", base+"/1",
+		"application/json", `{"age": "old"}`, &errResp))
+	assert.Equal("invalid request values", errResp["error"])
+	assert.Contains(errResp["fields"], "age")
+	assert.Equal(http.StatusBadRequest, do(t, "PUT", base+"/1",
+		"application/json", `{}`, nil))
+
+	// delete
+	assert.Equal(http.StatusNoContent, do(t, "DELETE", base+"/2", "", "", nil))
+	assert.Equal(http.StatusNotFound, do(t, "DELETE", base+"/2", "", "", nil))
+
+	assert.Equal(http.StatusMethodNotAllowed, do(t, "DELETE", base, "", "", nil))
+	assert.Equal(http.StatusMethodNotAllowed, do(t, "POST", base+"/1", "", "",
+		nil))
+}
+
+func TestHandlerList(t *testing.T) {
+	assert := assert.New(t)
+	db, srv := newServer(t, &Options{Query: dbx.QueryRules{
+		Filters:  map[string][]string{"nickname": nil, "age": {dbx.QUERY_GT}},
+		Sortable: []string{"age"}, DefaultLimit: 2, MaxLimit: 3,
+	}})
+	base := srv.URL + "/users"
+	for i := 0; i < 5; i++ {
+		_, err := db.T("user").Insert(&User{
+			Userid: fmt.Sprintf("u%d", i), Nickname: []string{"a", "b"}[i%2],
+			Age: 20 + i,
+		})
+		assert.Nil(err)
+	}
+
+	type result struct {
+		Items []User `json:"items"`
+		Total int    `json:"total"`
+		Page  int    `json:"page"`
+		Limit int    `json:"limit"`
+	}
+	res := result{}
+	assert.Equal(http.StatusOK, do(t, "GET", base, "", "", &res))
+	assert.Equal(5, res.Total)
+	assert.Equal(2, len(res.Items))
+	assert.Equal(2, res.Limit)
+	assert.Equal(1, res.Page)
+
+	res = result{}
+	assert.Equal(http.StatusOK, do(t, "GET",
+		base+"/?nickname=a&sort=-age&page=2&limit=2", "", "", &res))
+	assert.Equal(3, res.Total)
+	assert.Equal(1, len(res.Items))
+	assert.Equal(20, res.Items[0].Age)
+	assert.Equal(2, res.Page)
+
+	// values are typed by fields
+	res = result{}
+	assert.Equal(http.StatusOK, do(t, "GET", base+"?age__gt=22&sort=id&limit=3",
+		"", "", &res))
+	assert.Equal(2, res.Total)
+	assert.Equal([]int64{4, 5}, []int64{res.Items[0].Id, res.Items[1].Id})
+	assert.Equal(3, res.Limit)
+	assert.Equal(http.StatusBadRequest, do(t, "GET", base+"?age__gt=old", "",
+		"", nil))
+
+	// rows are ordered by primary key by default and for ties
+	res = result{}
+	assert.Equal(http.StatusOK, do(t, "GET", base+"?page=2", "", "", &res))
+	assert.Equal([]int64{3, 4}, []int64{res.Items[0].Id, res.Items[1].Id})
+	for i := 0; i < 2; i++ {
+		_, err := db.T("user").Insert(&User{Userid: "t", Age: 40})
+		assert.Nil(err)
+	}
+	res = result{}
+	assert.Equal(http.StatusOK, do(t, "GET", base+"?sort=-age", "", "", &res))
+	assert.Equal([]int64{6, 7}, []int64{res.Items[0].Id, res.Items[1].Id})
+	assert.Equal(http.StatusBadRequest, do(t, "GET",
+		base+"?page="+strconv.Itoa(math.MaxInt), "", "", nil))
+
+	// parameters out of whitelists are rejected or ignored
+	assert.Equal(http.StatusBadRequest, do(t, "GET", base+"?sort=userid", "",
+		"", nil))
+	assert.Equal(http.StatusBadRequest, do(t, "GET", base+"?age=20", "", "",
+		nil))
+	assert.Equal(http.StatusBadRequest, do(t, "GET", base+"?limit=4", "", "",
+		nil))
+	res = result{}
+	assert.Equal(http.StatusOK, do(t, "GET", base+"?userid=u1&limit=3", "",
+		"", &res))
+	assert.Equal(7, res.Total)
+}
+
+func TestHandlerAuthorize(t *testing.T) {
+	assert := assert.New(t)
+	actions := []string{}
+	db, srv := newServer(t, &Options{
+		Authorize: func(r *http.Request, action string, row interface{}) error {
+			actions = append(actions, action)
+			if r.Header.Get("Authorization") == "" {
+				return fmt.Errorf("login required")
+			}
+			if u, ok := row.(*User); ok && u.Userid == "admin" &&
+				action != ACTION_GET {
+				return &Error{Status: http.StatusConflict,
+					Message: "admin is read only"}
+			}
+			return nil
+		},
+	})
+	base := srv.URL + "/users"
+	_, err := db.T("user").Insert(&User{Userid: "admin"})
+	assert.Nil(err)
+
+	errResp := map[string]interface{}{}
+	assert.Equal(http.StatusForbidden, do(t, "GET", base, "", "", &errResp))
+	assert.Equal("login required", errResp["error"])
+
+	req := httptest.NewRequest("DELETE", "/1", nil)
+	req.Header.Set("Authorization", "token")
+	w := httptest.NewRecorder()
+	Handler(db, "user", &Options{Authorize: func(r *http.Request,
+		action string, row interface{}) error {
+		actions = append(actions, action)
+		return nil
+	}}).ServeHTTP(w, req)
+	assert.Equal(http.StatusNoContent, w.Code)
+
+	_, err = db.T("user").Insert(&User{Userid: "admin"})
+	assert.Nil(err)
+	req, err = http.NewRequest("DELETE", base+"/2", nil)
+	assert.Nil(err)
+	req.Header.Set("Authorization", "token")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusConflict, resp.StatusCode)
+	assert.Equal([]string{ACTION_LIST, ACTION_DELETE, ACTION_DELETE}, actions)
+}
+
+func TestHandlerAuthorizeUpdate(t *testing.T) {
+	assert := assert.New(t)
+	// rows are owned by the user of their userid
+	db, srv := newServer(t, &Options{
+		Authorize: func(r *http.Request, action string, row interface{}) error {
+			if u, ok := row.(*User); ok && u.Userid != r.Header.Get("User") {
+				return fmt.Errorf("not owner")
+			}
+			return nil
+		},
+	})
+	base := srv.URL + "/users"
+	_, err := db.T("user").Insert(&User{Userid: "alice", Age: 20})
+	assert.Nil(err)
+
+	put := func(body string) int {
+		req, err := http.NewRequest("PUT", base+"/1", strings.NewReader(body))
+		assert.Nil(err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User", "alice")
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	// the row can't be handed to others
+	assert.Equal(http.StatusForbidden, put(`{"userid": "bob"}`))
+	assert.Equal(http.StatusOK, put(`{"age": 21}`))
+
+	u := User{}
+	assert.Nil(db.T("user").SelectAll().Filter("id=?", 1).One(&u))
+	assert.Equal(User{Id: 1, Userid: "alice", Age: 21}, u)
+}
+
+func TestHandlerScope(t *testing.T) {
+	assert := assert.New(t)
+	db, srv := newServer(t, &Options{
+		Query: dbx.QueryRules{Filters: map[string][]string{"age": nil}},
+		Scope: func(r *http.Request, s *dbx.SQLSelector) *dbx.SQLSelector {
+			return s.Filter("userid=?", r.Header.Get("User"))
+		},
+	})
+	for _, u := range []User{{Userid: "alice", Age: 20},
+		{Userid: "bob", Age: 20}, {Userid: "alice", Age: 30}} {
+		_, err := db.T("user").Insert(&u)
+		assert.Nil(err)
+	}
+
+	list := func(query string) ListResult {
+		req, err := http.NewRequest("GET", srv.URL+"/users"+query, nil)
+		assert.Nil(err)
+		req.Header.Set("User", "alice")
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(err)
+		defer resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		res := ListResult{}
+		assert.Nil(json.NewDecoder(resp.Body).Decode(&res))
+		return res
+	}
+	res := list("")
+	assert.Equal(2, res.Total)
+	assert.Equal(2, len(res.Items.([]interface{})))
+	res = list("?age=20")
+	assert.Equal(1, res.Total)
+	assert.Equal("alice",
+		res.Items.([]interface{})[0].(map[string]interface{})["userid"])
+}
+
+type Account struct {
+	Id        int64         `json:"id"         db:"id"         sqlite:"INTEGER PRIMARY KEY AUTOINCREMENT"`
+	Userid    string        `json:"userid"     db:"userid"     sqlite:"TEXT NOT NULL"`
+	Nickname  string        `json:"nickname"   db:"nickname"   sqlite:"TEXT"`
+	Password  string        `json:"password"   db:"password"   sqlite:"TEXT"`
+	Version   int64         `json:"version"    db:"version"    sqlite:"INTEGER NOT NULL DEFAULT 0" dbx:"version"`
+	DeletedAt sql.NullInt64 `json:"deleted_at" db:"deleted_at" sqlite:"INTEGER" dbx:"softDelete"`
+}
+
+func TestHandlerColumns(t *testing.T) {
+	assert := assert.New(t)
+	dbx.SetLogger(nil)
+	db := dbxtest.Open(t, dbxtest.Table{Name: "account", Row: Account{}})
+	srv := httptest.NewServer(http.StripPrefix("/accounts",
+		Handler(db, "account", &Options{
+			Writable: []string{"nickname", "password"},
+			Hidden:   []string{"password"},
+		})))
+	t.Cleanup(srv.Close)
+	base := srv.URL + "/accounts"
+	_, err := db.T("account").Insert(&Account{Userid: "u1", Password: "p1"})
+	assert.Nil(err)
+
+	// columns out of Writable are ignored, hidden columns aren't responded
+	resp := map[string]interface{}{}
+	assert.Equal(http.StatusCreated, do(t, "POST", base, "application/json",
+		`{"userid": "u2", "nickname": "n2", "password": "p2", "version": 5}`,
+		&resp))
+	assert.Equal(map[string]interface{}{"id": float64(2), "userid": "",
+		"nickname": "n2", "version": float64(0),
+		"deleted_at": map[string]interface{}{"Int64": float64(0),
+			"Valid": false}}, resp)
+
+	resp = map[string]interface{}{}
+	assert.Equal(http.StatusOK, do(t, "PUT", base+"/1", "application/json",
+		`{"userid": "u3", "nickname": "n1", "version": 9,
+		"deleted_at": 1}`, &resp))
+	assert.Equal("u1", resp["userid"])
+	assert.Equal(float64(1), resp["version"])
+	assert.NotContains(resp, "password")
+	assert.Equal(http.StatusBadRequest, do(t, "PUT", base+"/1",
+		"application/json", `{"userid": "u3"}`, nil))
+
+	account := Account{}
+	assert.Nil(db.T("account").SelectAll().Filter("id=?", 1).One(&account))
+	assert.Equal(Account{Id: 1, Userid: "u1", Nickname: "n1", Password: "p1",
+		Version: 1}, account)
+
+	list := struct {
+		Items []map[string]interface{} `json:"items"`
+	}{}
+	assert.Equal(http.StatusOK, do(t, "GET", base, "", "", &list))
+	assert.Equal(2, len(list.Items))
+	assert.NotContains(list.Items[0], "password")
+	assert.Equal("n1", list.Items[0]["nickname"])
+}
+
+func TestHandlerErrors(t *testing.T) {
+	assert := assert.New(t)
+	dbx.SetLogger(nil)
+	db := dbxtest.Open(t, dbxtest.Table{Name: "account", Row: Account{}})
+	logs := bytes.Buffer{}
+	bumped := false
+	srv := httptest.NewServer(http.StripPrefix("/accounts",
+		Handler(db, "account", &Options{
+			ErrorLog: log.New(&logs, "", 0),
+			Authorize: func(r *http.Request, action string,
+				row interface{}) error {
+				switch action {
+				case ACTION_DELETE:
+					return &Error{Message: "no"}
+				case ACTION_UPDATE:
+					// the row is changed by others before it's saved
+					if !bumped {
+						bumped = true
+						_, err := db.DB().Exec(
+							"UPDATE account SET version=version+1")
+						return err
+					}
+				}
+				return nil
+			},
+		})))
+	t.Cleanup(srv.Close)
+	base := srv.URL + "/accounts"
+	_, err := db.T("account").Insert(&Account{Userid: "u1"})
+	assert.Nil(err)
+
+	// *Error without status is forbidden
+	errResp := map[string]interface{}{}
+	assert.Equal(http.StatusForbidden, do(t, "DELETE", base+"/1", "", "",
+		&errResp))
+	assert.Equal("no", errResp["error"])
+
+	errResp = map[string]interface{}{}
+	assert.Equal(http.StatusConflict, do(t, "PUT", base+"/1",
+		"application/json", `{"nickname": "n1"}`, &errResp))
+	assert.Contains(errResp["error"], "stale row")
+
+	// details of internal errors are only logged
+	assert.Nil(db.DropTable("account"))
+	errResp = map[string]interface{}{}
+	assert.Equal(http.StatusInternalServerError, do(t, "GET", base+"/1", "",
+		"", &errResp))
+	assert.Equal("internal server error", errResp["error"])
+	assert.Contains(logs.String(), "GET /1")
+	assert.Contains(logs.String(), "no such table")
+}
+
+func TestHandlerBodySize(t *testing.T) {
+	assert := assert.New(t)
+	_, srv := newServer(t, &Options{MaxBodyBytes: 64})
+	base := srv.URL + "/users"
+
+	assert.Equal(http.StatusCreated, do(t, "POST", base, "application/json",
+		`{"userid": "u1"}`, nil))
+	long := strings.Repeat("a", 64)
+	errResp := map[string]interface{}{}
+	assert.Equal(http.StatusRequestEntityTooLarge, do(t, "POST", base,
+		"application/json", `{"userid": "`+long+`"}`, &errResp))
+	assert.Equal("request body too large", errResp["error"])
+	assert.Equal(http.StatusRequestEntityTooLarge, do(t, "PUT", base+"/1",
+		"application/x-www-form-urlencoded",
+		url.Values{"nick": {long}}.Encode(), nil))
+}
+
+func TestHandlerPanics(t *testing.T) {
+	assert := assert.New(t)
+	db, _ := newServer(t, nil)
+	assert.Panics(func() { Handler(db, "no_such_table", nil) })
+	assert.Panics(func() {
+		Handler(db, "user", &Options{Query: dbx.QueryRules{
+			Sortable: []string{"no_such_column"}}})
+	})
+	assert.Panics(func() {
+		Handler(db, "user", &Options{Query: dbx.QueryRules{
+			Filters: map[string][]string{"no_such_column": nil}}})
+	})
+	assert.Panics(func() {
+		Handler(db, "user", &Options{Hidden: []string{"no_such_column"}})
+	})
+}
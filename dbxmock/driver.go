@@ -0,0 +1,136 @@
+package dbxmock
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+)
+
+// connector connects to the mock
+type connector struct {
+	mock *Mock
+}
+
+func (this *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &conn{mock: this.mock}, nil
+}
+
+func (this *connector) Driver() driver.Driver {
+	return mockDriver{}
+}
+
+type mockDriver struct{}
+
+func (mockDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("dbxmock: use New to open a mock database")
+}
+
+// conn passes statements to expectations of the mock
+type conn struct {
+	mock *Mock
+}
+
+func (this *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: this, query: query}, nil
+}
+
+func (this *conn) Close() error {
+	return nil
+}
+
+func (this *conn) Begin() (driver.Tx, error) {
+	return this.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (this *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (
+	driver.Tx, error) {
+	if _, err := this.mock.next(kindBegin, "", nil); err != nil {
+		return nil, err
+	}
+	return &tx{mock: this.mock}, nil
+}
+
+func (this *conn) ExecContext(ctx context.Context, query string,
+	args []driver.NamedValue) (driver.Result, error) {
+	e, err := this.mock.next(kindExec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return e.result, nil
+}
+
+func (this *conn) QueryContext(ctx context.Context, query string,
+	args []driver.NamedValue) (driver.Rows, error) {
+	e, err := this.mock.next(kindQuery, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.structs != nil {
+		return this.mock.structRows(query, e.structs)
+	}
+	if e.rows != nil {
+		return e.rows.driverRows()
+	}
+	return &rows{columns: []string{}}, nil
+}
+
+// stmt is only used if database/sql prepares statements
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (this *stmt) Close() error {
+	return nil
+}
+
+func (this *stmt) NumInput() int {
+	return -1
+}
+
+func (this *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return this.conn.ExecContext(context.Background(), this.query,
+		namedValues(args))
+}
+
+func (this *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return this.conn.QueryContext(context.Background(), this.query,
+		namedValues(args))
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+type tx struct {
+	mock *Mock
+}
+
+func (this *tx) Commit() error {
+	_, err := this.mock.next(kindCommit, "", nil)
+	return err
+}
+
+func (this *tx) Rollback() error {
+	_, err := this.mock.next(kindRollback, "", nil)
+	return err
+}
+
+// result implements driver.Result
+type result struct {
+	lastInsertId int64
+	rowsAffected int64
+}
+
+func (this result) LastInsertId() (int64, error) {
+	return this.lastInsertId, nil
+}
+
+func (this result) RowsAffected() (int64, error) {
+	return this.rowsAffected, nil
+}
@@ -0,0 +1,247 @@
+// Package dbxmock provides a mock database for testing code built on dbx
+// without a real database. Statements issued by dbx are matched against
+// scripted expectations in order, and the expectations return results, rows
+// or errors to dbx as a driver does:
+//
+//	db, mock := dbxmock.New(dbx.DRIVER_SQLITE3)
+//	db.RegisterTable("user", User{})
+//	mock.ExpectQuery("SELECT .* FROM user WHERE userid=?").WithArgs("u1").
+//		WillReturnStructs(User{Id: 1, Userid: "u1"})
+//	...
+//	if err := mock.ExpectationsWereMet(); err != nil {
+//		t.Error(err)
+//	}
+package dbxmock
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sync"
+
+	dbx "github.com/eschao/go-dbx"
+)
+
+// kinds of expectations
+const (
+	kindExec     = "exec"
+	kindQuery    = "query"
+	kindBegin    = "begin"
+	kindCommit   = "commit"
+	kindRollback = "rollback"
+)
+
+type anyArg struct{}
+
+// AnyArg matches any argument in WithArgs, e.g. a timestamp
+var AnyArg interface{} = anyArg{}
+
+// Mock holds expectations of a mock database and records calls issued by
+// dbx
+type Mock struct {
+	db           *dbx.Database
+	mutex        sync.Mutex
+	expectations []*Expectation
+	calls        []dbx.Call
+}
+
+// New returns a database backed by a new mock, dialect is the driver of
+// generated SQL, empty means SQLite
+func New(dialect string) (*dbx.Database, *Mock) {
+	if dialect == "" {
+		dialect = dbx.DRIVER_SQLITE3
+	}
+
+	mock := &Mock{}
+	mock.db = dbx.NewDatabase()
+	mock.db.OpenDB(dialect, sql.OpenDB(&connector{mock: mock}))
+	mock.db.Use(dbx.InterceptorFunc(func(call *dbx.Call,
+		next func() error) error {
+		mock.mutex.Lock()
+		mock.calls = append(mock.calls, *call)
+		mock.mutex.Unlock()
+		return next()
+	}))
+	return mock.db, mock
+}
+
+// Calls returns all calls issued by dbx with their table and operation,
+// including unexpected ones
+func (this *Mock) Calls() []dbx.Call {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	calls := make([]dbx.Call, len(this.calls))
+	copy(calls, this.calls)
+	return calls
+}
+
+// ExpectExec expects a statement matching the regular expression, use
+// regexp.QuoteMeta to match literally. It affects one row by default
+func (this *Mock) ExpectExec(expr string) *Expectation {
+	e := this.expect(kindExec, expr)
+	e.result = result{rowsAffected: 1}
+	return e
+}
+
+// ExpectQuery expects a query matching the regular expression, use
+// regexp.QuoteMeta to match literally. It returns no row by default
+func (this *Mock) ExpectQuery(expr string) *Expectation {
+	return this.expect(kindQuery, expr)
+}
+
+// ExpectBegin expects beginning a transaction
+func (this *Mock) ExpectBegin() *Expectation {
+	return this.expect(kindBegin, "")
+}
+
+// ExpectCommit expects committing a transaction
+func (this *Mock) ExpectCommit() *Expectation {
+	return this.expect(kindCommit, "")
+}
+
+// ExpectRollback expects rolling back a transaction
+func (this *Mock) ExpectRollback() *Expectation {
+	return this.expect(kindRollback, "")
+}
+
+func (this *Mock) expect(kind, expr string) *Expectation {
+	e := &Expectation{mock: this, kind: kind}
+	if expr != "" {
+		e.re = regexp.MustCompile(expr)
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.expectations = append(this.expectations, e)
+	return e
+}
+
+// ExpectationsWereMet returns an error if any expectation is not consumed
+func (this *Mock) ExpectationsWereMet() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, e := range this.expectations {
+		if !e.consumed {
+			return fmt.Errorf("dbxmock: expectation is not met: %s", e)
+		}
+	}
+	return nil
+}
+
+// next consumes the next expectation if it matches the call
+func (this *Mock) next(kind, q string, args []driver.NamedValue) (
+	*Expectation, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	var e *Expectation
+	for _, v := range this.expectations {
+		if !v.consumed {
+			e = v
+			break
+		}
+	}
+	if e == nil {
+		return nil, fmt.Errorf("dbxmock: unexpected %s %s", kind, q)
+	}
+
+	if e.kind != kind {
+		return nil, fmt.Errorf("dbxmock: unexpected %s %s, expecting %s",
+			kind, q, e)
+	}
+	if e.re != nil && !e.re.MatchString(q) {
+		return nil, fmt.Errorf("dbxmock: %s doesn't match %s", q, e)
+	}
+	if err := e.matchArgs(args); err != nil {
+		return nil, err
+	}
+	e.consumed = true
+	return e, e.err
+}
+
+// Expectation is an expected call of mock database
+type Expectation struct {
+	mock     *Mock
+	kind     string
+	re       *regexp.Regexp
+	args     []interface{}
+	hasArgs  bool
+	result   driver.Result
+	rows     *Rows
+	structs  []interface{}
+	err      error
+	consumed bool
+}
+
+func (this *Expectation) String() string {
+	if this.re == nil {
+		return this.kind
+	}
+	s := this.kind + " " + this.re.String()
+	if this.hasArgs {
+		s += fmt.Sprintf(" with args %v", this.args)
+	}
+	return s
+}
+
+// WithArgs expects the statement is executed with the given arguments, use
+// AnyArg to match any value
+func (this *Expectation) WithArgs(args ...interface{}) *Expectation {
+	this.args = args
+	this.hasArgs = true
+	return this
+}
+
+// WillReturnResult sets the result of exec expectation
+func (this *Expectation) WillReturnResult(lastInsertId,
+	rowsAffected int64) *Expectation {
+	this.result = result{lastInsertId: lastInsertId, rowsAffected: rowsAffected}
+	return this
+}
+
+// WillReturnRows sets the rows of query expectation
+func (this *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	this.rows = rows
+	return this
+}
+
+// WillReturnStructs sets the rows of query expectation by structs of
+// registered tables, columns are read from fields by the selected columns of
+// query. A row of joined tables is given by a []interface{} of structs
+func (this *Expectation) WillReturnStructs(rows ...interface{}) *Expectation {
+	this.structs = rows
+	return this
+}
+
+// WillReturnError makes the expectation fail with err
+func (this *Expectation) WillReturnError(err error) *Expectation {
+	this.err = err
+	return this
+}
+
+func (this *Expectation) matchArgs(args []driver.NamedValue) error {
+	if !this.hasArgs {
+		return nil
+	}
+	if len(args) != len(this.args) {
+		return fmt.Errorf("dbxmock: %s is executed with %d args, expecting %s",
+			this.re, len(args), this)
+	}
+
+	for i, a := range this.args {
+		if a == AnyArg {
+			continue
+		}
+		v, err := driver.DefaultParameterConverter.ConvertValue(a)
+		if err != nil {
+			return fmt.Errorf("dbxmock: invalid arg %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(v, args[i].Value) {
+			return fmt.Errorf("dbxmock: arg %d of %s is %v, expecting %v",
+				i, this.re, args[i].Value, v)
+		}
+	}
+	return nil
+}
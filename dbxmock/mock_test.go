@@ -0,0 +1,136 @@
+package dbxmock
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	dbx "github.com/eschao/go-dbx"
+	"github.com/stretchr/testify/assert"
+)
+
+type User struct {
+	Id       int64  `db:"id"       sqlite:"INTEGER PRIMARY KEY AUTOINCREMENT"`
+	Userid   string `db:"userid"   sqlite:"TEXT NOT NULL"`
+	Nickname string `db:"nickname" sqlite:"TEXT"`
+}
+
+type Login struct {
+	Id     int64  `db:"id"      sqlite:"INTEGER PRIMARY KEY AUTOINCREMENT"`
+	Userid string `db:"userid"  sqlite:"TEXT NOT NULL"`
+	LastIP int64  `db:"last_ip" sqlite:"INTEGER"`
+}
+
+func newMock(t *testing.T) (*dbx.Database, *Mock) {
+	db, mock := New("")
+	assert.Nil(t, db.RegisterTable("user", User{}))
+	assert.Nil(t, db.RegisterTable("login", Login{}))
+	return db, mock
+}
+
+func TestExec(t *testing.T) {
+	assert := assert.New(t)
+	db, mock := newMock(t)
+
+	mock.ExpectExec("^INSERT INTO user").WillReturnResult(10, 1)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE user SET nickname=? WHERE userid=?")).
+		WithArgs("nick", "u1").WillReturnResult(0, 2)
+	mock.ExpectExec("^DELETE FROM user").WithArgs(AnyArg).
+		WillReturnError(fmt.Errorf("failed"))
+
+	rs, err := db.T("user").Insert(&User{Userid: "u1"})
+	assert.Nil(err)
+	id, _ := rs.LastInsertId()
+	assert.Equal(int64(10), id)
+
+	rs, err = db.T("user").Update("userid=?", "u1").Set("nickname").
+		Values("nick")
+	assert.Nil(err)
+	n, _ := rs.RowsAffected()
+	assert.Equal(int64(2), n)
+
+	assert.EqualError(db.T("user").Delete("id=?", 1), "failed")
+	assert.Nil(mock.ExpectationsWereMet())
+
+	calls := mock.Calls()
+	assert.Equal(3, len(calls))
+	assert.Equal("user", calls[1].Table)
+	assert.Equal(dbx.OP_UPDATE, calls[1].Op)
+	assert.Equal([]interface{}{"nick", "u1"}, calls[1].Args)
+}
+
+func TestQuery(t *testing.T) {
+	assert := assert.New(t)
+	db, mock := newMock(t)
+
+	mock.ExpectQuery("FROM user WHERE userid=\\?").WithArgs("u1").
+		WillReturnStructs(User{Id: 1, Userid: "u1", Nickname: "nick"})
+	mock.ExpectQuery("FROM user").WillReturnStructs(
+		&User{Id: 1, Userid: "u1"}, &User{Id: 2, Userid: "u2"})
+	mock.ExpectQuery("COUNT").WillReturnRows(NewRows("count").AddRow(2))
+	mock.ExpectQuery("JOIN login").WillReturnStructs(
+		[]interface{}{User{Id: 1, Userid: "u1"}, Login{LastIP: 100}})
+
+	user := User{}
+	assert.Nil(db.T("user").SelectAll().Filter("userid=?", "u1").One(&user))
+	assert.Equal(User{Id: 1, Userid: "u1", Nickname: "nick"}, user)
+
+	users := []User{}
+	assert.Nil(db.T("user").Select("id", "userid").All(&users))
+	assert.Equal([]User{{Id: 1, Userid: "u1"}, {Id: 2, Userid: "u2"}}, users)
+
+	n, err := db.T("user").CountAll()
+	assert.Nil(err)
+	assert.Equal(2, n)
+
+	user = User{}
+	login := Login{}
+	assert.Nil(db.T("user").Select("id", "userid").
+		LeftJoin("login", "userid", "userid").Select("last_ip").
+		One(&user, &login))
+	assert.Equal(int64(1), user.Id)
+	assert.Equal(int64(100), login.LastIP)
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestTransaction(t *testing.T) {
+	assert := assert.New(t)
+	db, mock := newMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("^INSERT INTO user")
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("^INSERT INTO user").WillReturnError(fmt.Errorf("failed"))
+	mock.ExpectRollback()
+
+	insert := func(tx *dbx.Transaction) error {
+		_, err := tx.T("user").Insert(&User{Userid: "u1"})
+		return err
+	}
+	assert.Nil(db.InTx(context.Background(), nil, insert))
+	assert.EqualError(db.InTx(context.Background(), nil, insert), "failed")
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestUnexpected(t *testing.T) {
+	assert := assert.New(t)
+	db, mock := newMock(t)
+
+	// no expectation
+	_, err := db.T("user").CountAll()
+	assert.NotNil(err)
+
+	// mismatched arguments
+	mock.ExpectExec("^DELETE FROM user").WithArgs(1)
+	assert.NotNil(db.T("user").Delete("id=?", 2))
+	assert.NotNil(mock.ExpectationsWereMet())
+
+	// mismatched kind
+	_, err = db.T("user").CountAll()
+	assert.NotNil(err)
+
+	assert.Nil(db.T("user").Delete("id=?", 1))
+	assert.Nil(mock.ExpectationsWereMet())
+}
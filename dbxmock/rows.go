@@ -0,0 +1,163 @@
+package dbxmock
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Rows are the scripted rows of a query
+type Rows struct {
+	columns []string
+	values  [][]interface{}
+}
+
+// NewRows returns empty rows of the given columns
+func NewRows(columns ...string) *Rows {
+	return &Rows{columns: columns}
+}
+
+// AddRow adds a row with values of columns
+func (this *Rows) AddRow(values ...interface{}) *Rows {
+	this.values = append(this.values, values)
+	return this
+}
+
+// driverRows converts rows to driver rows
+func (this *Rows) driverRows() (*rows, error) {
+	rs := &rows{columns: this.columns}
+	for _, r := range this.values {
+		if len(r) != len(this.columns) {
+			return nil, fmt.Errorf("dbxmock: row has %d values, but %d columns",
+				len(r), len(this.columns))
+		}
+		vals, err := driverValues(r)
+		if err != nil {
+			return nil, err
+		}
+		rs.values = append(rs.values, vals)
+	}
+	return rs, nil
+}
+
+// structRows converts structs of registered tables to driver rows of the
+// selected columns of q
+func (this *Mock) structRows(q string, structs []interface{}) (*rows, error) {
+	columns := selectColumns(q)
+	if columns == nil {
+		return nil, fmt.Errorf("dbxmock: can't find selected columns in %s", q)
+	}
+
+	rs := &rows{columns: make([]string, len(columns))}
+	for i, c := range columns {
+		rs.columns[i] = c.name
+	}
+	for _, s := range structs {
+		row, ok := s.([]interface{})
+		if !ok {
+			row = []interface{}{s}
+		}
+
+		vals := make([]interface{}, len(columns))
+		for i, c := range columns {
+			v, err := this.columnValue(row, c)
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = v
+		}
+		dvals, err := driverValues(vals)
+		if err != nil {
+			return nil, err
+		}
+		rs.values = append(rs.values, dvals)
+	}
+	return rs, nil
+}
+
+// columnValue returns value of column c from the first struct which has it
+func (this *Mock) columnValue(row []interface{}, c selectColumn) (
+	interface{}, error) {
+	for _, s := range row {
+		val := reflect.Indirect(reflect.ValueOf(s))
+		for _, name := range this.db.TableNames() {
+			if c.table != "" && c.table != name {
+				continue
+			}
+			t, _ := this.db.GetTableSchema(name)
+			if t.RowType() != val.Type() {
+				continue
+			}
+			if col, ok := t.Columns[c.name]; ok {
+				return val.Field(col.Index).Interface(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("dbxmock: no struct has column %s", c.name)
+}
+
+type selectColumn struct {
+	table string
+	name  string
+}
+
+// selectColumns parses the selected columns of a query generated by dbx
+func selectColumns(q string) []selectColumn {
+	if !strings.HasPrefix(q, "SELECT ") {
+		return nil
+	}
+	end := strings.Index(q, " FROM ")
+	if end < 0 {
+		return nil
+	}
+
+	columns := []selectColumn{}
+	for _, s := range strings.Split(q[len("SELECT "):end], ",") {
+		s = strings.TrimSpace(s)
+		c := selectColumn{name: s}
+		if i := strings.Index(s, "."); i > 0 {
+			c.table = s[:i]
+			c.name = s[i+1:]
+		}
+		columns = append(columns, c)
+	}
+	return columns
+}
+
+func driverValues(vals []interface{}) ([]driver.Value, error) {
+	dvals := make([]driver.Value, len(vals))
+	for i, v := range vals {
+		dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("dbxmock: invalid value %v: %v", v, err)
+		}
+		dvals[i] = dv
+	}
+	return dvals, nil
+}
+
+// rows implements driver.Rows
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	pos     int
+}
+
+func (this *rows) Columns() []string {
+	return this.columns
+}
+
+func (this *rows) Close() error {
+	return nil
+}
+
+func (this *rows) Next(dest []driver.Value) error {
+	if this.pos >= len(this.values) {
+		return io.EOF
+	}
+	copy(dest, this.values[this.pos])
+	this.pos++
+	return nil
+}
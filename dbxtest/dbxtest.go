@@ -0,0 +1,80 @@
+// Package dbxtest provides test helpers which run every test in a fresh
+// in-memory SQLite database and roll back its changes when the test ends:
+//
+//	func TestCreateUser(t *testing.T) {
+//		tx := dbxtest.New(t, dbxtest.Table{Name: "user", Row: User{}})
+//		_, err := tx.T("user").Insert(&User{Userid: "u1"})
+//		...
+//	}
+package dbxtest
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	dbx "github.com/eschao/go-dbx"
+)
+
+// Table is a table to register and create in test database
+type Table struct {
+	Name string
+	Row  interface{}
+}
+
+var dbCount int64
+
+// Open opens a fresh in-memory SQLite database with shared cache, registers
+// and creates the given tables. The database is closed when the test ends
+func Open(t testing.TB, tables ...Table) *dbx.Database {
+	t.Helper()
+
+	n := atomic.AddInt64(&dbCount, 1)
+	dsn := fmt.Sprintf("file:dbxtest_%d?mode=memory&cache=shared", n)
+	db := dbx.NewDatabase()
+	if err := db.OpenSQLite(dsn); err != nil {
+		t.Fatalf("dbxtest: open database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	for _, table := range tables {
+		if err := db.RegisterTable(table.Name, table.Row); err != nil {
+			t.Fatalf("dbxtest: register %s table: %v", table.Name, err)
+		}
+		if err := db.CreateTable(table.Name); err != nil {
+			t.Fatalf("dbxtest: create %s table: %v", table.Name, err)
+		}
+	}
+	return db
+}
+
+// New opens a database as Open does and begins a transaction in it, the
+// transaction is rolled back when the test ends
+func New(t testing.TB, tables ...Table) *dbx.Transaction {
+	t.Helper()
+
+	db := Open(t, tables...)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("dbxtest: begin transaction: %v", err)
+	}
+	t.Cleanup(func() {
+		tx.Rollback()
+	})
+	return tx
+}
+
+// Savepoint begins a nested transaction of tx which is rolled back when the
+// test ends, e.g. for subtests sharing the data prepared in tx
+func Savepoint(t testing.TB, tx *dbx.Transaction) *dbx.Transaction {
+	t.Helper()
+
+	sp, err := tx.Begin()
+	if err != nil {
+		t.Fatalf("dbxtest: begin savepoint: %v", err)
+	}
+	t.Cleanup(func() {
+		sp.Rollback()
+	})
+	return sp
+}
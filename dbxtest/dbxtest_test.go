@@ -0,0 +1,73 @@
+package dbxtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type User struct {
+	Id     int64  `db:"id"     sqlite:"INTEGER PRIMARY KEY AUTOINCREMENT"`
+	Userid string `db:"userid" sqlite:"TEXT NOT NULL"`
+}
+
+var userTable = Table{Name: "user", Row: User{}}
+
+func TestNew(t *testing.T) {
+	assert := assert.New(t)
+
+	tx := New(t, userTable)
+	_, err := tx.T("user").Insert(&User{Userid: "u1"})
+	assert.Nil(err)
+	n, err := tx.T("user").CountAll()
+	assert.Nil(err)
+	assert.Equal(1, n)
+
+	// every test has its own database
+	other := New(t, userTable)
+	n, err = other.T("user").CountAll()
+	assert.Nil(err)
+	assert.Equal(0, n)
+}
+
+func TestRollback(t *testing.T) {
+	assert := assert.New(t)
+
+	db := Open(t, userTable)
+	t.Run("insert", func(t *testing.T) {
+		tx, err := db.Begin()
+		assert.Nil(err)
+		t.Cleanup(func() {
+			tx.Rollback()
+		})
+		_, err = tx.T("user").Insert(&User{Userid: "u1"})
+		assert.Nil(err)
+	})
+
+	n, err := db.T("user").CountAll()
+	assert.Nil(err)
+	assert.Equal(0, n)
+}
+
+func TestSavepoint(t *testing.T) {
+	assert := assert.New(t)
+
+	tx := New(t, userTable)
+	_, err := tx.T("user").Insert(&User{Userid: "u1"})
+	assert.Nil(err)
+
+	for _, userid := range []string{"u2", "u3"} {
+		t.Run(userid, func(t *testing.T) {
+			sp := Savepoint(t, tx)
+			_, err := sp.T("user").Insert(&User{Userid: userid})
+			assert.Nil(err)
+			n, err := sp.T("user").CountAll()
+			assert.Nil(err)
+			assert.Equal(2, n)
+		})
+	}
+
+	n, err := tx.T("user").CountAll()
+	assert.Nil(err)
+	assert.Equal(1, n)
+}
@@ -0,0 +1,82 @@
+package dbx
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrDryRun is returned by queries returning *sql.Rows in dry-run mode
+var ErrDryRun = errors.New("dbx: no rows in dry-run mode")
+
+// Statement is a statement recorded in dry-run mode
+type Statement struct {
+	SQL   string
+	Args  []interface{}
+	Table string
+	Op    string
+}
+
+type dryRunRecorder struct {
+	mutex      sync.Mutex
+	statements []Statement
+}
+
+// dryRunResult is the result of statements in dry-run mode, no row is
+// affected
+type dryRunResult struct{}
+
+func (dryRunResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (dryRunResult) RowsAffected() (int64, error) {
+	return 0, nil
+}
+
+// SetDryRun enables or disables dry-run mode. In dry-run mode, statements
+// executed through the builders of database are recorded instead of being
+// executed: exec statements affect no row, queries return no row (One
+// returns sql.ErrNoRows and Run returns ErrDryRun), and interceptors, loggers
+// and slow query handler are not called. Enabling it clears the recorded
+// statements. Transactions are still begun and committed by driver
+func (this *Database) SetDryRun(enable bool) {
+	if enable {
+		this.dryRun = &dryRunRecorder{}
+	} else {
+		this.dryRun = nil
+	}
+}
+
+// DryRunStatements returns statements recorded in dry-run mode
+func (this *Database) DryRunStatements() []Statement {
+	if this.dryRun == nil {
+		return nil
+	}
+
+	this.dryRun.mutex.Lock()
+	defer this.dryRun.mutex.Unlock()
+	statements := make([]Statement, len(this.dryRun.statements))
+	copy(statements, this.dryRun.statements)
+	return statements
+}
+
+func (this *Database) isDryRun() bool {
+	return this != nil && this.dryRun != nil
+}
+
+// dryRunning records the statement and returns true if database is in
+// dry-run mode
+func (this *sqlSession) dryRunning(table, op, q string,
+	args []interface{}) bool {
+	if !this.database.isDryRun() {
+		return false
+	}
+
+	r := this.database.dryRun
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.statements = append(r.statements, Statement{
+		SQL: q, Args: args, Table: table, Op: op,
+	})
+	return true
+}
@@ -0,0 +1,57 @@
+package dbx
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRun(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+
+	tDatabase.SetDryRun(true)
+	defer tDatabase.SetDryRun(false)
+
+	user := TestUsers[0]
+	rs, err := tDatabase.T(USER_TABLE).Insert(&user)
+	assert.Nil(err)
+	n, err := rs.RowsAffected()
+	assert.Nil(err)
+	assert.Equal(int64(0), n)
+
+	_, err = tDatabase.T(USER_TABLE).Update("userid=?", user.Userid).
+		Set("nickname").Values("nick")
+	assert.Nil(err)
+	assert.Nil(tDatabase.T(USER_TABLE).Delete("userid=?", user.Userid))
+
+	assert.Equal(sql.ErrNoRows, tDatabase.T(USER_TABLE).SelectAll().One(&user))
+	users := []User{}
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().All(&users))
+	assert.Empty(users)
+	_, err = tDatabase.T(USER_TABLE).Select("id").Run()
+	assert.Equal(ErrDryRun, err)
+
+	statements := tDatabase.DryRunStatements()
+	assert.Equal(6, len(statements))
+	ops := []string{}
+	for _, s := range statements {
+		assert.Equal(USER_TABLE, s.Table)
+		ops = append(ops, s.Op)
+	}
+	assert.Equal([]string{OP_INSERT, OP_UPDATE, OP_DELETE, OP_SELECT,
+		OP_SELECT, OP_SELECT}, ops)
+	assert.Equal("UPDATE user SET nickname=? WHERE userid=?",
+		statements[1].SQL)
+	assert.Equal([]interface{}{"nick", user.Userid}, statements[1].Args)
+
+	// nothing is executed
+	tDatabase.SetDryRun(false)
+	assert.Nil(tDatabase.DryRunStatements())
+	count, err := tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(0, count)
+}
@@ -0,0 +1,342 @@
+package dbx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// extensions of fixture files, JSON is parsed as YAML
+var fixtureExts = []string{".yml", ".yaml", ".json"}
+
+// FixtureOptions defines options of LoadFixtures
+type FixtureOptions struct {
+	// Truncate deletes all rows of the tables before loading fixtures
+	Truncate bool
+}
+
+// fixture is the rows of a table loaded from fixture file
+type fixture struct {
+	table *Table
+	rows  []map[string]interface{}
+}
+
+// LoadFixturesDir loads fixtures from files in dir, see LoadFixtures
+func (this *Database) LoadFixturesDir(dir string, opts *FixtureOptions) error {
+	return this.LoadFixtures(os.DirFS(dir), opts)
+}
+
+// LoadFixtures loads fixtures from files of fsys in a transaction. Every
+// file is named after a registered table with .yml, .yaml or .json
+// extension, and holds a list of rows or a map of labeled rows, keys of row
+// are columns of table:
+//
+//   - id: 1
+//     userid: u1
+//     update_time: '{{ ago "24h" }}'
+//
+// Tables are loaded in the order of their relations, so a table is loaded
+// after the tables it belongs to. Files are executed as text/template with
+// now, ago and fromNow functions returning DATETIME_FORMAT text of database
+// clock, e.g. {{ fromNow "1h" }}. Such text is converted to the timestamp
+// value of column as autoCreateTime does. Missing autoCreateTime and
+// autoUpdateTime columns are set to now
+func (this *Database) LoadFixtures(fsys fs.FS, opts *FixtureOptions) error {
+	if opts == nil {
+		opts = &FixtureOptions{}
+	}
+
+	fixtures, err := this.readFixtures(fsys)
+	if err != nil {
+		return err
+	}
+	order, err := this.fixtureOrder(fixtures)
+	if err != nil {
+		return err
+	}
+
+	return this.InTx(context.Background(), nil, func(tx *Transaction) error {
+		if opts.Truncate {
+			for i := len(order) - 1; i >= 0; i-- {
+				q := "DELETE FROM " + order[i]
+				if _, err := tx.session().exec(order[i], OP_DELETE, q); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, name := range order {
+			if err := tx.insertFixture(fixtures[name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// readFixtures reads fixture files of registered tables
+func (this *Database) readFixtures(fsys fs.FS) (map[string]*fixture, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	fixtures := map[string]*fixture{}
+	for _, e := range entries {
+		ext := ""
+		for _, v := range fixtureExts {
+			if strings.HasSuffix(e.Name(), v) {
+				ext = v
+			}
+		}
+		if e.IsDir() || ext == "" {
+			continue
+		}
+
+		name := strings.TrimSuffix(e.Name(), ext)
+		t, ok := this.tables[name]
+		if !ok {
+			return nil, fmt.Errorf("fixture %s has no registered table",
+				e.Name())
+		}
+		if _, ok := fixtures[name]; ok {
+			return nil, fmt.Errorf("%s table has more than one fixture", name)
+		}
+
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		rows, err := this.parseFixture(e.Name(), data)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			for k := range r {
+				if _, ok := t.Columns[k]; !ok {
+					return nil, fmt.Errorf("fixture %s: %s table has no column %s",
+						e.Name(), name, k)
+				}
+			}
+		}
+		fixtures[name] = &fixture{table: &t, rows: rows}
+	}
+	return fixtures, nil
+}
+
+// parseFixture executes fixture file as a template and parses its rows
+func (this *Database) parseFixture(file string, data []byte) (
+	[]map[string]interface{}, error) {
+	// times are formatted in local time zone as fixtureValue parses them
+	now := this.now().In(time.Local)
+	offset := func(d string, sign time.Duration) (string, error) {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return "", err
+		}
+		return now.Add(sign * v).Format(DATETIME_FORMAT), nil
+	}
+	tmpl, err := template.New(file).Funcs(template.FuncMap{
+		"now": func() string {
+			return now.Format(DATETIME_FORMAT)
+		},
+		"ago": func(d string) (string, error) {
+			return offset(d, -1)
+		},
+		"fromNow": func(d string) (string, error) {
+			return offset(d, 1)
+		},
+	}).Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.Buffer{}
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		return nil, fmt.Errorf("fixture %s: %v", file, err)
+	}
+
+	rows := []map[string]interface{}{}
+	switch d := doc.(type) {
+	case nil:
+	case []interface{}:
+		for _, r := range d {
+			row, err := fixtureRow(file, r)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, row)
+		}
+	case map[interface{}]interface{}:
+		labels := make([]string, 0, len(d))
+		labeled := make(map[string]interface{}, len(d))
+		for k, v := range d {
+			labels = append(labels, fmt.Sprint(k))
+			labeled[fmt.Sprint(k)] = v
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			row, err := fixtureRow(file, labeled[l])
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, row)
+		}
+	default:
+		return nil, fmt.Errorf("fixture %s must be a list or map of rows", file)
+	}
+	return rows, nil
+}
+
+func fixtureRow(file string, v interface{}) (map[string]interface{}, error) {
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("fixture %s has a row which is not a map", file)
+	}
+	row := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		row[fmt.Sprint(k)] = v
+	}
+	return row, nil
+}
+
+// fixtureOrder sorts tables of fixtures so that a table is after the tables
+// it belongs to
+func (this *Database) fixtureOrder(fixtures map[string]*fixture) (
+	[]string, error) {
+	deps := map[string][]string{}
+	for name := range fixtures {
+		for _, rel := range this.tables[name].Relations {
+			if _, ok := fixtures[rel.Table]; !ok || rel.Table == name {
+				continue
+			}
+			if rel.Kind == RELATION_BELONGS_TO {
+				deps[name] = append(deps[name], rel.Table)
+			} else {
+				deps[rel.Table] = append(deps[rel.Table], name)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(fixtures))
+	for name := range fixtures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	order := []string{}
+	state := map[string]int{} // 1: visiting, 2: visited
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("fixtures have circular relations on %s table",
+				name)
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		sort.Strings(deps[name])
+		for _, d := range deps[name] {
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// insertFixture inserts rows of fixture
+func (this *Transaction) insertFixture(f *fixture) error {
+	driver := this.db.driver
+	now := this.db.now()
+	for _, row := range f.rows {
+		cols := []string{}
+		for k := range row {
+			cols = append(cols, k)
+		}
+		for _, c := range f.table.orderedColumns() {
+			if _, ok := row[c.Name]; !ok &&
+				(c.IsAutoCreateTime || c.IsAutoUpdateTime) {
+				cols = append(cols, c.Name)
+			}
+		}
+		if len(cols) == 0 {
+			return fmt.Errorf("fixture of %s table has an empty row",
+				f.table.Name)
+		}
+		sort.Strings(cols)
+
+		args := make([]interface{}, len(cols))
+		for i, k := range cols {
+			c := f.table.Columns[k]
+			t := f.table.rowType.Field(c.Index).Type
+			v, ok := row[k]
+			if !ok {
+				v = now
+			}
+			arg, err := fixtureValue(c, t, driver, v)
+			if err != nil {
+				return fmt.Errorf("fixture of %s table, column %s: %v",
+					f.table.Name, k, err)
+			}
+			args[i] = arg
+		}
+
+		q := "INSERT INTO " + f.table.Name + "(" + strings.Join(cols, ",") +
+			") VALUES(" + strings.Repeat("?,", len(cols)-1) + "?)"
+		_, err := this.session().exec(f.table.Name, OP_INSERT, q, args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fixtureValue converts value of fixture to the SQL value of column c whose
+// field type is t
+func fixtureValue(c Column, t reflect.Type, driver string, v interface{}) (
+	interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	if s, ok := v.(string); ok {
+		if tm, err := time.ParseInLocation(DATETIME_FORMAT, s,
+			time.Local); err == nil {
+			v = tm
+		}
+	}
+	if tm, ok := v.(time.Time); ok {
+		if ts, err := timestampValue(c, t, driver, tm); err == nil {
+			return ts, nil
+		}
+	}
+
+	field := reflect.New(t).Elem()
+	if err := assignValue(field, v); err != nil {
+		return nil, err
+	}
+	return field.Interface(), nil
+}
@@ -0,0 +1,140 @@
+package dbx
+
+import (
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFixtures = fstest.MapFS{
+	"user_login.json": {Data: []byte(`{
+		"second": {"userid": "u2", "oauth_id": "o2", "last_ip": 2},
+		"first": {"userid": "u1", "oauth_id": "o1", "last_ip": 1}
+	}`)},
+	"user.yml": {Data: []byte(`
+- id: 1
+  userid: u1
+  nickname: first
+- id: 2
+  userid: u2
+  update_time: '{{ ago "1h" }}'
+`)},
+	"user_profile.yaml": {Data: []byte(`
+- userid: u1
+  age: 20
+  email: u1@example.com
+  created_at: '{{ ago "24h" }}'
+  deleted_at: null
+`)},
+	"README.md": {Data: []byte("not a fixture")},
+}
+
+func TestLoadFixtures(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	tDatabase.SetClock(func() time.Time { return now })
+	defer tDatabase.SetClock(nil)
+
+	for _, name := range []string{USER_TABLE, USER_LOGIN_TABLE,
+		USER_PROFILE_TABLE} {
+		tDatabase.DropTable(name)
+		assert.Nil(tDatabase.CreateTable(name))
+	}
+	assert.Nil(tDatabase.LoadFixtures(testFixtures, nil))
+
+	users := []User{}
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().NullAsZero().Asc("id").
+		All(&users))
+	assert.Equal(2, len(users))
+	assert.Equal("first", users[0].Nickname)
+	// text field of integer column gets unix seconds
+	assert.Equal(strconv.FormatInt(now.Add(-time.Hour).Unix(), 10),
+		users[1].UpdateTime)
+
+	// labeled rows are inserted in the order of labels
+	logins := []UserLogin{}
+	assert.Nil(tDatabase.T(USER_LOGIN_TABLE).SelectAll().NullAsZero().Asc("id").
+		All(&logins))
+	assert.Equal(2, len(logins))
+	assert.Equal("u1", logins[0].Userid)
+	assert.Equal(int64(2), logins[1].LastIP)
+
+	// timestamps
+	profile := UserProfile{}
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().NullAsZero().
+		One(&profile))
+	assert.Equal(now.Add(-24*time.Hour).Unix(), profile.CreatedAt)
+	assert.Equal(strconv.FormatInt(now.Unix(), 10), profile.UpdatedAt)
+	assert.Equal(int64(20), profile.Age.Int64)
+	assert.Equal("u1@example.com", *profile.Email)
+	assert.False(profile.DeletedAt.Valid)
+
+	// duplicated rows fail and nothing is loaded
+	assert.NotNil(tDatabase.LoadFixtures(testFixtures, nil))
+	n, err := tDatabase.T(USER_PROFILE_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(1, n)
+
+	// truncate before loading
+	assert.Nil(tDatabase.LoadFixtures(testFixtures,
+		&FixtureOptions{Truncate: true}))
+	n, err = tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(2, n)
+}
+
+func TestLoadFixturesClockZone(t *testing.T) {
+	assert := assert.New(t)
+
+	// clock of a time zone other than local
+	_, offset := time.Now().Zone()
+	zone := time.FixedZone("fixture", offset+5*3600)
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, zone)
+	tDatabase.SetClock(func() time.Time { return now })
+	defer tDatabase.SetClock(nil)
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+	assert.Nil(tDatabase.LoadFixtures(fstest.MapFS{
+		"user.yml": testFixtures["user.yml"],
+	}, nil))
+
+	user := User{}
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().NullAsZero().
+		Filter("id=?", 2).One(&user))
+	assert.Equal(strconv.FormatInt(now.Add(-time.Hour).Unix(), 10),
+		user.UpdateTime)
+}
+
+func TestFixtureErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NotNil(tDatabase.LoadFixtures(fstest.MapFS{
+		"no_such_table.yml": {Data: []byte("- id: 1")},
+	}, nil))
+	assert.NotNil(tDatabase.LoadFixtures(fstest.MapFS{
+		"user.yml": {Data: []byte("- no_such_column: 1")},
+	}, nil))
+	assert.NotNil(tDatabase.LoadFixtures(fstest.MapFS{
+		"user.yml": {Data: []byte(`- update_time: '{{ ago "x" }}'`)},
+	}, nil))
+	assert.NotNil(tDatabase.LoadFixtures(fstest.MapFS{
+		"user.yml": {Data: []byte("id: 1")},
+	}, nil))
+}
+
+func TestFixtureOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := map[string]*fixture{
+		USER_LOGIN_TABLE: {}, USER_OAUTH_TABLE: {}, USER_TABLE: {},
+	}
+	order, err := tDatabase.fixtureOrder(fixtures)
+	assert.Nil(err)
+	assert.Equal(USER_TABLE, order[0])
+	assert.Equal(3, len(order))
+}
@@ -1,11 +1,17 @@
 module github.com/eschao/go-dbx
 
-go 1.13
+go 1.23
 
 require (
 	github.com/go-sql-driver/mysql v1.4.0
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/mattn/go-sqlite3 v1.14.17
 	github.com/stretchr/testify v1.5.1
+	gopkg.in/yaml.v2 v2.2.2
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	google.golang.org/appengine v1.6.5 // indirect
 )
@@ -0,0 +1,81 @@
+package dbx
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// Row hooks, a row struct can implement any of them to be called by dbx. The
+// given executor is bound to the same table and transaction of the running
+// operation, returning an error from a Before hook aborts the operation and
+// an error from an After hook is returned to the caller
+
+// BeforeInserter is called before the row is inserted or replaced
+type BeforeInserter interface {
+	BeforeInsert(e *SQLExecutor) error
+}
+
+// AfterInserter is called after the row is inserted or replaced
+type AfterInserter interface {
+	AfterInsert(e *SQLExecutor) error
+}
+
+// BeforeUpdater is called before the row is updated by SQLUpdater.Value
+type BeforeUpdater interface {
+	BeforeUpdate(e *SQLExecutor) error
+}
+
+// AfterUpdater is called after the row is updated by SQLUpdater.Value
+type AfterUpdater interface {
+	AfterUpdate(e *SQLExecutor) error
+}
+
+// AfterFinder is called after the row is scanned by One or All
+type AfterFinder interface {
+	AfterFind(e *SQLExecutor) error
+}
+
+// BeforeDeleter is called before the row is deleted by SQLExecutor.DeleteRow
+type BeforeDeleter interface {
+	BeforeDelete(e *SQLExecutor) error
+}
+
+// AfterDeleter is called after the row is deleted by SQLExecutor.DeleteRow
+type AfterDeleter interface {
+	AfterDelete(e *SQLExecutor) error
+}
+
+// Tx returns the transaction of executor, nil if it's not in a transaction
+func (this *SQLExecutor) Tx() *sql.Tx {
+	return this.tx
+}
+
+// Table returns table schema of executor
+func (this *SQLExecutor) Table() *Table {
+	return this.table
+}
+
+// T returns executor of another table in the same database or transaction
+func (this *SQLExecutor) T(name string) *SQLExecutor {
+	e := *this
+	e.err = nil
+	e.table = this.tableGetter(name)
+	if e.table == nil || e.table.Name == "" {
+		e.err = fmt.Errorf("%s table is not registered", name)
+	}
+	return &e
+}
+
+// afterFind calls AfterFind hook of scanned rows, every row must be an
+// addressable struct value
+func afterFind(e *SQLExecutor, rows ...reflect.Value) error {
+	for _, row := range rows {
+		if h, ok := row.Addr().Interface().(AfterFinder); ok {
+			if err := h.AfterFind(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package dbx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var tHookCalls = []string{}
+
+type HookedProfile UserProfile
+
+func (this *HookedProfile) BeforeInsert(e *SQLExecutor) error {
+	tHookCalls = append(tHookCalls, "BeforeInsert:"+e.Table().Name)
+	if this.Userid == "" {
+		return fmt.Errorf("userid is empty")
+	}
+	this.Bio = strings.ToUpper(this.Bio)
+	return nil
+}
+
+func (this *HookedProfile) AfterInsert(e *SQLExecutor) error {
+	tHookCalls = append(tHookCalls, "AfterInsert")
+	return nil
+}
+
+func (this *HookedProfile) BeforeUpdate(e *SQLExecutor) error {
+	tHookCalls = append(tHookCalls, "BeforeUpdate")
+	if this.Bio == "" {
+		return fmt.Errorf("bio is empty")
+	}
+	return nil
+}
+
+func (this *HookedProfile) AfterUpdate(e *SQLExecutor) error {
+	tHookCalls = append(tHookCalls, "AfterUpdate")
+	return nil
+}
+
+func (this *HookedProfile) AfterFind(e *SQLExecutor) error {
+	this.Bio = strings.ToLower(this.Bio)
+	return nil
+}
+
+func (this *HookedProfile) BeforeDelete(e *SQLExecutor) error {
+	tHookCalls = append(tHookCalls, "BeforeDelete")
+	// hooks can query in the same transaction
+	n, err := e.T(USER_PROFILE_TABLE).Count("id=?", this.Id)
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return fmt.Errorf("row %d is not found", this.Id)
+	}
+	return nil
+}
+
+func (this *HookedProfile) AfterDelete(e *SQLExecutor) error {
+	tHookCalls = append(tHookCalls, "AfterDelete")
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_PROFILE_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_PROFILE_TABLE))
+	tHookCalls = []string{}
+
+	// insert
+	_, err := tDatabase.T(USER_PROFILE_TABLE).Insert(&HookedProfile{Bio: "bio"})
+	assert.NotNil(err)
+	profile := HookedProfile{Userid: TestUsers[0].Userid, Bio: "bio"}
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Insert(&profile)
+	assert.Nil(err)
+	assert.Equal([]string{
+		"BeforeInsert:user_profile", "BeforeInsert:user_profile", "AfterInsert",
+	}, tHookCalls)
+	n, err := tDatabase.T(USER_PROFILE_TABLE).Count("bio=?", "BIO")
+	assert.Nil(err)
+	assert.Equal(1, n)
+
+	// find
+	profiles := []HookedProfile{}
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().All(&profiles))
+	assert.Equal(1, len(profiles))
+	assert.Equal("bio", profiles[0].Bio)
+	profile = HookedProfile{}
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().One(&profile))
+	assert.Equal("bio", profile.Bio)
+
+	// update
+	tHookCalls = []string{}
+	profile.Bio = ""
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", profile.Id).
+		Set("bio").Value(&profile)
+	assert.NotNil(err)
+	profile.Bio = "new bio"
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", profile.Id).
+		Set("bio").Value(&profile)
+	assert.Nil(err)
+	assert.Equal([]string{"BeforeUpdate", "BeforeUpdate", "AfterUpdate"},
+		tHookCalls)
+
+	// delete in transaction
+	tHookCalls = []string{}
+	tx, err := tDatabase.Begin()
+	assert.Nil(err)
+	assert.Nil(tx.T(USER_PROFILE_TABLE).DeleteRow(&profile))
+	assert.Nil(tx.Commit())
+	assert.Equal([]string{"BeforeDelete", "AfterDelete"}, tHookCalls)
+	n, err = tDatabase.T(USER_PROFILE_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(0, n)
+}
@@ -0,0 +1,76 @@
+package dbx
+
+import (
+	"context"
+)
+
+// operations of transactions
+const (
+	OP_BEGIN    = "begin"
+	OP_COMMIT   = "commit"
+	OP_ROLLBACK = "rollback"
+)
+
+// Call describes a statement or transaction operation issued by dbx
+type Call struct {
+	// Ctx is the context of call, an interceptor can replace it before
+	// calling next, e.g. with a context carrying a tracing span
+	Ctx   context.Context
+	Op    string
+	Table string
+	// SQL and Args are empty for begin, commit and rollback
+	SQL  string
+	Args []interface{}
+}
+
+// Interceptor wraps calls of database, it must call next to run the call and
+// return its error, or an error of its own to abort it
+type Interceptor interface {
+	Intercept(call *Call, next func() error) error
+}
+
+// InterceptorFunc adapts a function to Interceptor
+type InterceptorFunc func(call *Call, next func() error) error
+
+func (f InterceptorFunc) Intercept(call *Call, next func() error) error {
+	return f(call, next)
+}
+
+// Use appends interceptors to the chain of database, the first one is the
+// outermost
+func (this *Database) Use(interceptors ...Interceptor) {
+	this.interceptors = append(this.interceptors, interceptors...)
+}
+
+// ResetInterceptors removes all interceptors of database
+func (this *Database) ResetInterceptors() {
+	this.interceptors = nil
+}
+
+// intercept runs fn through the interceptor chain
+func (this *Database) intercept(call *Call, fn func() error) error {
+	if this == nil || len(this.interceptors) == 0 {
+		return fn()
+	}
+
+	next := fn
+	for i := len(this.interceptors) - 1; i >= 0; i-- {
+		ic, n := this.interceptors[i], next
+		next = func() error {
+			return ic.Intercept(call, n)
+		}
+	}
+	return next()
+}
+
+func (this *Transaction) call(op string) *Call {
+	return &Call{Ctx: this.Context(), Op: op}
+}
+
+// WithContext returns an executor whose statements are executed with the
+// given context
+func (this *SQLExecutor) WithContext(ctx context.Context) *SQLExecutor {
+	e := *this
+	e.ctx = ctx
+	return &e
+}
@@ -0,0 +1,90 @@
+package dbx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey string
+
+func TestInterceptor(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+
+	calls := []string{}
+	tDatabase.Use(InterceptorFunc(func(call *Call, next func() error) error {
+		calls = append(calls, "outer:"+call.Op+":"+call.Table)
+		return next()
+	}), InterceptorFunc(func(call *Call, next func() error) error {
+		calls = append(calls, "inner:"+call.Op)
+		if v, ok := call.Ctx.Value(ctxKey("trace")).(string); ok {
+			calls = append(calls, "trace:"+v)
+		}
+		return next()
+	}))
+	defer tDatabase.ResetInterceptors()
+
+	ctx := context.WithValue(context.Background(), ctxKey("trace"), "t1")
+	_, err := tDatabase.T(USER_TABLE).WithContext(ctx).Insert(&TestUsers[0])
+	assert.Nil(err)
+	assert.Equal([]string{"outer:insert:user", "inner:insert", "trace:t1"},
+		calls)
+
+	// transaction
+	calls = calls[:0]
+	assert.Nil(tDatabase.InTx(ctx, nil, func(tx *Transaction) error {
+		_, err := tx.T(USER_TABLE).CountAll()
+		return err
+	}))
+	assert.Equal([]string{
+		"outer:begin:", "inner:begin", "trace:t1",
+		"outer:count:user", "inner:count", "trace:t1",
+		"outer:commit:", "inner:commit", "trace:t1",
+	}, calls)
+
+	calls = calls[:0]
+	tx, err := tDatabase.Begin()
+	assert.Nil(err)
+	assert.Nil(tx.Rollback())
+	assert.Equal([]string{"outer:begin:", "inner:begin", "outer:rollback:",
+		"inner:rollback"}, calls)
+
+	// context replaced at begin is the context of transaction
+	calls = calls[:0]
+	tDatabase.ResetInterceptors()
+	tDatabase.Use(InterceptorFunc(func(call *Call, next func() error) error {
+		if call.Op == OP_BEGIN {
+			call.Ctx = context.WithValue(call.Ctx, ctxKey("span"), "s1")
+		}
+		if v, ok := call.Ctx.Value(ctxKey("span")).(string); ok {
+			calls = append(calls, call.Op+":"+v)
+		}
+		return next()
+	}))
+	tx, err = tDatabase.BeginTx(ctx, nil)
+	assert.Nil(err)
+	assert.Equal("s1", tx.Context().Value(ctxKey("span")))
+	assert.Equal("t1", tx.Context().Value(ctxKey("trace")))
+	_, err = tx.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Nil(tx.Commit())
+	assert.Equal([]string{"begin:s1", "count:s1", "commit:s1"}, calls)
+
+	// abort by interceptor
+	errDenied := fmt.Errorf("denied")
+	tDatabase.Use(InterceptorFunc(func(call *Call, next func() error) error {
+		if call.Op == OP_DELETE {
+			return errDenied
+		}
+		return next()
+	}))
+	assert.Equal(errDenied, tDatabase.T(USER_TABLE).Delete(""))
+	n, err := tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(1, n)
+}
@@ -0,0 +1,299 @@
+package dbx
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ColumnSchema is a column of an existing table read from database
+type ColumnSchema struct {
+	Name string
+	// Type is the declared type, e.g. INTEGER or varchar(32)
+	Type     string
+	Nullable bool
+	// Default is the default expression of column, nil if it has no default
+	Default         *string
+	IsPrimaryKey    bool
+	IsAutoIncrement bool
+	// IsRowidAlias is set for an INTEGER PRIMARY KEY of SQLite without
+	// AUTOINCREMENT, its values are assigned automatically as well
+	IsRowidAlias bool
+	IsUnique     bool
+}
+
+// TableSchema is an existing table read from database
+type TableSchema struct {
+	Name    string
+	Columns []ColumnSchema
+	// Indexes are indexes of table except primary key, sorted by name
+	Indexes []TableIndex
+}
+
+// Column returns the column of the given name, nil if it's not found
+func (this *TableSchema) Column(name string) *ColumnSchema {
+	for i := range this.Columns {
+		if strings.EqualFold(this.Columns[i].Name, name) {
+			return &this.Columns[i]
+		}
+	}
+	return nil
+}
+
+// Introspect reads schema of the given tables from database, all tables if
+// no name is given. Columns are in the order of table definition
+func (this *Database) Introspect(names ...string) ([]TableSchema, error) {
+	if this.db == nil {
+		return nil, fmt.Errorf("no opened database")
+	}
+
+	var err error
+	if len(names) == 0 {
+		if names, err = this.existingTables(); err != nil {
+			return nil, err
+		}
+	}
+
+	tables := make([]TableSchema, 0, len(names))
+	for _, name := range names {
+		var t *TableSchema
+		switch this.driver {
+		case DRIVER_SQLITE3:
+			t, err = this.introspectSQLite(name)
+		case DRIVER_MYSQL:
+			t, err = this.introspectMySQL(name)
+		default:
+			err = fmt.Errorf("unsupportted driver %s for introspection",
+				this.driver)
+		}
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, *t)
+	}
+	return tables, nil
+}
+
+// existingTables returns names of tables in database
+func (this *Database) existingTables() ([]string, error) {
+	q := ""
+	switch this.driver {
+	case DRIVER_SQLITE3:
+		q = "SELECT name FROM sqlite_master WHERE type='table' AND " +
+			"name NOT LIKE 'sqlite_%' ORDER BY name"
+	case DRIVER_MYSQL:
+		q = "SELECT TABLE_NAME FROM information_schema.TABLES WHERE " +
+			"TABLE_SCHEMA=DATABASE() AND TABLE_TYPE='BASE TABLE' " +
+			"ORDER BY TABLE_NAME"
+	default:
+		return nil, fmt.Errorf("unsupportted driver %s for introspection",
+			this.driver)
+	}
+
+	names := []string{}
+	err := this.session().query("", OP_SELECT, q, nil,
+		func(rs *sql.Rows) (int64, error) {
+			for rs.Next() {
+				name := ""
+				if err := rs.Scan(&name); err != nil {
+					return int64(len(names)), err
+				}
+				names = append(names, name)
+			}
+			return int64(len(names)), nil
+		})
+	return names, err
+}
+
+func (this *Database) introspectSQLite(name string) (*TableSchema, error) {
+	s := this.session()
+	createSQL := ""
+	err := s.queryRow(name, OP_SELECT,
+		"SELECT sql FROM sqlite_master WHERE type='table' AND name=?",
+		[]interface{}{name}, &createSQL)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%s table doesn't exist", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	autoIncrement := strings.Contains(strings.ToUpper(createSQL),
+		"AUTOINCREMENT")
+	pks := 0
+
+	t := &TableSchema{Name: name}
+	q := "PRAGMA table_info(" + quoteIdent(name) + ")"
+	err = s.query(name, OP_SELECT, q, nil, func(rs *sql.Rows) (int64, error) {
+		for rs.Next() {
+			var cid, notNull, pk int
+			var dflt sql.NullString
+			c := ColumnSchema{}
+			if err := rs.Scan(&cid, &c.Name, &c.Type, &notNull, &dflt,
+				&pk); err != nil {
+				return 0, err
+			}
+			c.Nullable = notNull == 0 && pk == 0
+			if dflt.Valid {
+				c.Default = &dflt.String
+			}
+			c.IsPrimaryKey = pk > 0
+			if c.IsPrimaryKey {
+				pks++
+			}
+			t.Columns = append(t.Columns, c)
+		}
+		return int64(len(t.Columns)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// an INTEGER PRIMARY KEY is an alias of rowid which is assigned
+	// automatically even without AUTOINCREMENT
+	for i := range t.Columns {
+		c := &t.Columns[i]
+		c.IsAutoIncrement = c.IsPrimaryKey && pks == 1 &&
+			(autoIncrement || strings.EqualFold(c.Type, "INTEGER"))
+		c.IsRowidAlias = c.IsAutoIncrement && !autoIncrement
+	}
+
+	// indexes and unique constraints, primary key is excluded
+	indexes := []TableIndex{}
+	q = "PRAGMA index_list(" + quoteIdent(name) + ")"
+	err = s.query(name, OP_SELECT, q, nil, func(rs *sql.Rows) (int64, error) {
+		cols, err := rs.Columns()
+		if err != nil {
+			return 0, err
+		}
+		vals := make([]interface{}, len(cols))
+		for rs.Next() {
+			var index, origin string
+			var unique int
+			for i, c := range cols {
+				switch c {
+				case "name":
+					vals[i] = &index
+				case "unique":
+					vals[i] = &unique
+				case "origin":
+					vals[i] = &origin
+				default:
+					vals[i] = new(interface{})
+				}
+			}
+			if err := rs.Scan(vals...); err != nil {
+				return 0, err
+			}
+			if origin != "pk" {
+				indexes = append(indexes, TableIndex{
+					Name: index, IsUnique: unique == 1,
+				})
+			}
+		}
+		return int64(len(indexes)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, index := range indexes {
+		cols := []string{}
+		q = "PRAGMA index_info(" + quoteIdent(index.Name) + ")"
+		err = s.query(name, OP_SELECT, q, nil,
+			func(rs *sql.Rows) (int64, error) {
+				for rs.Next() {
+					var seq, cid int
+					var col string
+					if err := rs.Scan(&seq, &cid, &col); err != nil {
+						return 0, err
+					}
+					cols = append(cols, col)
+				}
+				return int64(len(cols)), nil
+			})
+		if err != nil {
+			return nil, err
+		}
+		index.Columns = cols
+		t.Indexes = append(t.Indexes, index)
+		if index.IsUnique && len(cols) == 1 {
+			if c := t.Column(cols[0]); c != nil {
+				c.IsUnique = true
+			}
+		}
+	}
+	sort.Slice(t.Indexes, func(i, j int) bool {
+		return t.Indexes[i].Name < t.Indexes[j].Name
+	})
+	return t, nil
+}
+
+func (this *Database) introspectMySQL(name string) (*TableSchema, error) {
+	t := &TableSchema{Name: name}
+	q := "SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, " +
+		"COLUMN_KEY, EXTRA FROM information_schema.COLUMNS WHERE " +
+		"TABLE_SCHEMA=DATABASE() AND TABLE_NAME=? ORDER BY ORDINAL_POSITION"
+	err := this.session().query(name, OP_SELECT, q, []interface{}{name},
+		func(rs *sql.Rows) (int64, error) {
+			for rs.Next() {
+				var nullable, key, extra string
+				var dflt sql.NullString
+				c := ColumnSchema{}
+				if err := rs.Scan(&c.Name, &c.Type, &nullable, &dflt, &key,
+					&extra); err != nil {
+					return 0, err
+				}
+				c.Nullable = nullable == "YES"
+				if dflt.Valid {
+					c.Default = &dflt.String
+				}
+				c.IsPrimaryKey = key == "PRI"
+				c.IsUnique = key == "UNI"
+				c.IsAutoIncrement = strings.Contains(
+					strings.ToLower(extra), "auto_increment")
+				t.Columns = append(t.Columns, c)
+			}
+			return int64(len(t.Columns)), nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Columns) == 0 {
+		return nil, fmt.Errorf("%s table doesn't exist", name)
+	}
+
+	q = "SELECT INDEX_NAME, NON_UNIQUE, COLUMN_NAME FROM " +
+		"information_schema.STATISTICS WHERE TABLE_SCHEMA=DATABASE() AND " +
+		"TABLE_NAME=? AND INDEX_NAME<>'PRIMARY' ORDER BY INDEX_NAME, SEQ_IN_INDEX"
+	err = this.session().query(name, OP_SELECT, q, []interface{}{name},
+		func(rs *sql.Rows) (int64, error) {
+			n := int64(0)
+			for rs.Next() {
+				var index, col string
+				var nonUnique int
+				if err := rs.Scan(&index, &nonUnique, &col); err != nil {
+					return n, err
+				}
+				n++
+				last := len(t.Indexes) - 1
+				if last < 0 || t.Indexes[last].Name != index {
+					t.Indexes = append(t.Indexes, TableIndex{
+						Name: index, IsUnique: nonUnique == 0,
+					})
+					last++
+				}
+				t.Indexes[last].Columns = append(t.Indexes[last].Columns, col)
+			}
+			return n, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// quoteIdent quotes an identifier for PRAGMA statements
+func quoteIdent(name string) string {
+	return "\"" + strings.Replace(name, "\"", "\"\"", -1) + "\""
+}
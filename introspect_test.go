@@ -0,0 +1,65 @@
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntrospect(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_LOGIN_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_LOGIN_TABLE))
+
+	tables, err := tDatabase.Introspect(USER_LOGIN_TABLE)
+	assert.Nil(err)
+	assert.Equal(1, len(tables))
+	table := tables[0]
+	assert.Equal(USER_LOGIN_TABLE, table.Name)
+	assert.Equal(len(tDatabase.tables[USER_LOGIN_TABLE].Columns),
+		len(table.Columns))
+
+	id := table.Column("id")
+	assert.NotNil(id)
+	assert.True(id.IsPrimaryKey)
+	assert.True(id.IsAutoIncrement)
+	assert.False(id.Nullable)
+
+	userid := table.Column("userid")
+	assert.NotNil(userid)
+	assert.True(userid.IsUnique)
+	assert.False(userid.Nullable)
+	assert.False(userid.IsPrimaryKey)
+
+	lastLogin := table.Column("last_login")
+	assert.NotNil(lastLogin)
+	assert.Nil(table.Column("no_such_column"))
+
+	// all tables
+	tables, err = tDatabase.Introspect()
+	assert.Nil(err)
+	names := []string{}
+	for _, t := range tables {
+		names = append(names, t.Name)
+	}
+	assert.Contains(names, USER_LOGIN_TABLE)
+
+	_, err = tDatabase.Introspect("no_such_table")
+	assert.NotNil(err)
+}
+
+func TestIntrospectIndexes(t *testing.T) {
+	assert := assert.New(t)
+
+	db := NewDatabase()
+	assert.Nil(db.OpenSQLite(":memory:"))
+	defer db.Close()
+	db.DB().SetMaxOpenConns(1)
+	assert.Nil(db.RegisterTable("indexed_user", IndexedUser{}))
+	assert.Nil(db.CreateTables())
+
+	tables, err := db.Introspect("indexed_user")
+	assert.Nil(err)
+	assert.Equal(db.tables["indexed_user"].Indexes, tables[0].Indexes)
+}
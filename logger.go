@@ -0,0 +1,93 @@
+package dbx
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// operations of query events
+const (
+	OP_SELECT  = "select"
+	OP_COUNT   = "count"
+	OP_INSERT  = "insert"
+	OP_REPLACE = "replace"
+	OP_UPDATE  = "update"
+	OP_DELETE  = "delete"
+	OP_CREATE  = "create"
+	OP_DROP    = "drop"
+	OP_EXEC    = "exec"
+)
+
+// QueryEvent describes an executed SQL statement
+type QueryEvent struct {
+	SQL  string
+	Args []interface{}
+	// Duration is the elapsed time of execution, for a query it's the time
+	// until rows are scanned if dbx scans them, otherwise until rows are
+	// returned by driver
+	Duration time.Duration
+	// RowsAffected is the number of affected rows of exec, or the number of
+	// scanned rows of query, -1 if it's unknown
+	RowsAffected int64
+	Err          error
+	Table        string
+	Op           string
+}
+
+// Logger receives query events after statements are executed
+type Logger interface {
+	Log(ev *QueryEvent)
+}
+
+// LoggerFunc adapts a function to Logger
+type LoggerFunc func(ev *QueryEvent)
+
+func (f LoggerFunc) Log(ev *QueryEvent) {
+	f(ev)
+}
+
+// String formats the event in one line
+func (this *QueryEvent) String() string {
+	s := fmt.Sprintf("SQL: %s %v (%s", this.SQL, this.Args, this.Duration)
+	if this.RowsAffected >= 0 {
+		s += fmt.Sprintf(", %d rows", this.RowsAffected)
+	}
+	s += ")"
+	if this.Err != nil {
+		s += " error: " + this.Err.Error()
+	}
+	return s
+}
+
+// NewStdLogger returns a Logger which prints events by the standard log
+// package, nil means the standard logger
+func NewStdLogger(l *log.Logger) Logger {
+	return LoggerFunc(func(ev *QueryEvent) {
+		if l == nil {
+			log.Print(ev.String())
+		} else {
+			l.Print(ev.String())
+		}
+	})
+}
+
+// NewKVLogger returns a Logger which calls structured logging functions with
+// key-value pairs, e.g. NewKVLogger(slog.Info, slog.Error). The error
+// function is used for failed statements, nil means using info for all
+func NewKVLogger(info, errorf func(msg string, kv ...interface{})) Logger {
+	return LoggerFunc(func(ev *QueryEvent) {
+		kv := []interface{}{
+			"sql", ev.SQL, "args", ev.Args, "duration", ev.Duration,
+			"rows", ev.RowsAffected, "table", ev.Table, "op", ev.Op,
+		}
+		if ev.Err != nil {
+			kv = append(kv, "error", ev.Err)
+			if errorf != nil {
+				errorf("dbx query failed", kv...)
+				return
+			}
+		}
+		info("dbx query", kv...)
+	})
+}
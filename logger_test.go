@@ -0,0 +1,144 @@
+package dbx
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseLogger(t *testing.T) {
+	assert := assert.New(t)
+
+	events := []QueryEvent{}
+	tDatabase.SetLogger(LoggerFunc(func(ev *QueryEvent) {
+		events = append(events, *ev)
+	}))
+	defer tDatabase.SetLogger(nil)
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+	assert.Equal(2, len(events))
+	assert.Equal(OP_DROP, events[0].Op)
+	assert.Equal(OP_CREATE, events[1].Op)
+	assert.Equal(USER_TABLE, events[1].Table)
+
+	// exec
+	events = events[:0]
+	_, err := tDatabase.T(USER_TABLE).Insert(&TestUsers[0])
+	assert.Nil(err)
+	assert.Equal(1, len(events))
+	assert.Equal(OP_INSERT, events[0].Op)
+	assert.Equal(USER_TABLE, events[0].Table)
+	assert.True(strings.HasPrefix(events[0].SQL, "INSERT INTO "+USER_TABLE))
+	assert.Equal(len(tDatabase.tables[USER_TABLE].Columns)-1,
+		len(events[0].Args))
+	assert.Equal(int64(1), events[0].RowsAffected)
+	assert.Nil(events[0].Err)
+
+	// query
+	events = events[:0]
+	users := []User{}
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().
+		Filter("userid=?", TestUsers[0].Userid).All(&users))
+	assert.Equal(1, len(events))
+	assert.Equal(OP_SELECT, events[0].Op)
+	assert.Equal([]interface{}{TestUsers[0].Userid}, events[0].Args)
+	assert.Equal(int64(1), events[0].RowsAffected)
+
+	events = events[:0]
+	n, err := tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(1, n)
+	assert.Equal(1, len(events))
+	assert.Equal(OP_COUNT, events[0].Op)
+
+	// update and delete
+	events = events[:0]
+	_, err = tDatabase.T(USER_TABLE).Update("userid=?", TestUsers[0].Userid).
+		Set("nickname").Values("new name")
+	assert.Nil(err)
+	assert.Nil(tDatabase.T(USER_TABLE).Delete("userid=?", TestUsers[0].Userid))
+	assert.Equal(2, len(events))
+	assert.Equal(OP_UPDATE, events[0].Op)
+	assert.Equal([]interface{}{"new name", TestUsers[0].Userid}, events[0].Args)
+	assert.Equal(int64(1), events[0].RowsAffected)
+	assert.Equal(OP_DELETE, events[1].Op)
+
+	// error
+	events = events[:0]
+	assert.NotNil(tDatabase.T(USER_TABLE).Delete("no_such_column=?", 1))
+	assert.Equal(1, len(events))
+	assert.NotNil(events[0].Err)
+	assert.Equal(int64(-1), events[0].RowsAffected)
+}
+
+func TestQueryLogger(t *testing.T) {
+	assert := assert.New(t)
+
+	dbEvents := 0
+	tDatabase.SetLogger(LoggerFunc(func(ev *QueryEvent) {
+		dbEvents++
+	}))
+	defer tDatabase.SetLogger(nil)
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+	dbEvents = 0
+
+	events := []*QueryEvent{}
+	e := tDatabase.T(USER_TABLE).WithLogger(LoggerFunc(func(ev *QueryEvent) {
+		events = append(events, ev)
+	}))
+	_, err := e.Insert(&TestUsers[0])
+	assert.Nil(err)
+	user := User{}
+	assert.Nil(e.SelectAll().One(&user))
+	assert.Equal(2, len(events))
+	assert.Equal(OP_INSERT, events[0].Op)
+	assert.Equal(OP_SELECT, events[1].Op)
+	assert.Equal(0, dbEvents)
+
+	// logger of database is not changed
+	_, err = tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(1, dbEvents)
+}
+
+func TestLoggerAdapters(t *testing.T) {
+	assert := assert.New(t)
+
+	ev := &QueryEvent{
+		SQL: "SELECT * FROM user WHERE id=?", Args: []interface{}{1},
+		RowsAffected: 1, Table: "user", Op: OP_SELECT,
+	}
+
+	buf := bytes.Buffer{}
+	NewStdLogger(log.New(&buf, "", 0)).Log(ev)
+	assert.Equal("SQL: SELECT * FROM user WHERE id=? [1] (0s, 1 rows)\n",
+		buf.String())
+
+	infos := [][]interface{}{}
+	errors := [][]interface{}{}
+	logger := NewKVLogger(func(msg string, kv ...interface{}) {
+		infos = append(infos, kv)
+	}, func(msg string, kv ...interface{}) {
+		errors = append(errors, kv)
+	})
+	logger.Log(ev)
+	assert.Equal(1, len(infos))
+	assert.Equal([]interface{}{
+		"sql", ev.SQL, "args", ev.Args, "duration", ev.Duration,
+		"rows", int64(1), "table", "user", "op", OP_SELECT,
+	}, infos[0])
+
+	ev.Err = fmt.Errorf("failed")
+	ev.RowsAffected = -1
+	logger.Log(ev)
+	assert.Equal(1, len(infos))
+	assert.Equal(1, len(errors))
+	assert.Equal(ev.Err, errors[0][len(errors[0])-1])
+}
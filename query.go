@@ -0,0 +1,251 @@
+package dbx
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// operators of query filters, a filter parameter is column__op=value and
+// column=value is QUERY_EQ
+const (
+	QUERY_EQ   = "eq"
+	QUERY_NE   = "ne"
+	QUERY_GT   = "gt"
+	QUERY_GTE  = "gte"
+	QUERY_LT   = "lt"
+	QUERY_LTE  = "lte"
+	QUERY_LIKE = "like"
+	// QUERY_IN matches comma separated values, e.g. id__in=1,2,3
+	QUERY_IN = "in"
+	// QUERY_NULL matches NULL columns for true and others for false
+	QUERY_NULL = "null"
+)
+
+// SQL of operators except QUERY_IN and QUERY_NULL
+var queryOps = map[string]string{
+	QUERY_EQ: "=", QUERY_NE: "<>", QUERY_GT: ">", QUERY_GTE: ">=",
+	QUERY_LT: "<", QUERY_LTE: "<=", QUERY_LIKE: "LIKE",
+}
+
+// parameters of sorting and pagination
+const (
+	QUERY_SORT  = "sort"
+	QUERY_LIMIT = "limit"
+	QUERY_PAGE  = "page"
+)
+
+// QueryRules whitelists columns and operators of FromQuery
+type QueryRules struct {
+	// Filters maps columns which can be filtered to their allowed operators,
+	// all operators are allowed if they're empty
+	Filters map[string][]string
+	// Sortable are columns which can be sorted
+	Sortable []string
+	// DefaultLimit is the limit if it's not given, no limit if it's 0
+	DefaultLimit int
+	// MaxLimit is the max limit which can be given, no max if it's 0
+	MaxLimit int
+	// MaxIn is the max number of values of QUERY_IN, default is 100, so a
+	// request can't exceed the variable limit of driver
+	MaxIn int
+}
+
+// default max number of values of QUERY_IN
+const defaultMaxIn = 100
+
+// FromQuery adds filters, sorting and pagination of URL query parameters to
+// selector, strictly limited to columns and operators of rules:
+//
+//	?nickname__like=ch%&update_time__gt=2019-01-01&sort=-update_time,id&limit=20&page=2
+//
+// Filters are ANDed with the filter of selector. Values are converted to the
+// type of column fields as BindRequest does, and time values of timestamp
+// columns to their SQL values. Parameters which aren't of whitelisted columns
+// are ignored, and invalid ones are reported as the selector error
+func (this *SQLSelector) FromQuery(values url.Values,
+	rules *QueryRules) *SQLSelector {
+	if this.err != nil {
+		return this
+	}
+	if rules == nil {
+		rules = &QueryRules{}
+	}
+	if err := this.fromQuery(values, rules); err != nil {
+		this.err = fmt.Errorf("invalid query: %v", err)
+	}
+	return this
+}
+
+func (this *SQLSelector) fromQuery(values url.Values, rules *QueryRules) error {
+	for col := range rules.Filters {
+		if _, ok := this.table.Columns[col]; !ok {
+			return fmt.Errorf("%s table has no column %s", this.table.Name, col)
+		}
+	}
+	for _, col := range rules.Sortable {
+		if _, ok := this.table.Columns[col]; !ok {
+			return fmt.Errorf("%s table has no column %s", this.table.Name, col)
+		}
+	}
+
+	maxIn := rules.MaxIn
+	if maxIn <= 0 {
+		maxIn = defaultMaxIn
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	conds := []string{}
+	args := []interface{}{}
+	for _, k := range keys {
+		col, op := k, QUERY_EQ
+		if i := strings.LastIndex(k, "__"); i > 0 {
+			col, op = k[:i], k[i+2:]
+		}
+		allowed, ok := rules.Filters[col]
+		if !ok {
+			continue
+		}
+		if len(allowed) > 0 && !containsString(allowed, op) {
+			return fmt.Errorf("%s can't be filtered by %s", col, op)
+		}
+		for _, v := range values[k] {
+			cond, condArgs, err := this.queryCond(this.table.Columns[col], op, v,
+				maxIn)
+			if err != nil {
+				return fmt.Errorf("%s: %v", k, err)
+			}
+			conds = append(conds, cond)
+			args = append(args, condArgs...)
+		}
+	}
+	if len(conds) > 0 {
+		where := strings.Join(conds, " AND ")
+		if this.filter.where != "" {
+			where = "(" + this.filter.where + ") AND " + where
+		}
+		this.filter.where = where
+		// args of Filter may be the caller's slice, never append to it
+		this.filter.args = append(append([]interface{}{}, this.filter.args...),
+			args...)
+	}
+
+	if s := values.Get(QUERY_SORT); s != "" {
+		cols := strings.Split(s, ",")
+		for _, c := range cols {
+			if !containsString(rules.Sortable, strings.TrimPrefix(c, "-")) {
+				return fmt.Errorf("can't sort by %s", c)
+			}
+		}
+		this.OrderBy(cols...)
+	}
+
+	limit := rules.DefaultLimit
+	if s := values.Get(QUERY_LIMIT); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid limit %q", s)
+		}
+		limit = n
+	}
+	if rules.MaxLimit > 0 && (limit == 0 || limit > rules.MaxLimit) {
+		if values.Get(QUERY_LIMIT) != "" {
+			return fmt.Errorf("limit must be at most %d", rules.MaxLimit)
+		}
+		limit = rules.MaxLimit
+	}
+	if limit > 0 {
+		this.Limit(limit)
+	}
+
+	if s := values.Get(QUERY_PAGE); s != "" {
+		page, err := strconv.Atoi(s)
+		if err != nil || page < 1 {
+			return fmt.Errorf("invalid page %q", s)
+		}
+		if limit == 0 {
+			return fmt.Errorf("page needs a limit")
+		}
+		if page > math.MaxInt/limit {
+			return fmt.Errorf("page %d is out of range", page)
+		}
+		this.Offset((page - 1) * limit)
+	}
+	return nil
+}
+
+// queryCond returns the condition of column c by operator op with value v,
+// QUERY_IN accepts at most maxIn values
+func (this *SQLSelector) queryCond(c Column, op, v string, maxIn int) (string,
+	[]interface{}, error) {
+	switch op {
+	case QUERY_NULL:
+		isNull, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", nil, fmt.Errorf("converting %q to bool: %v", v, err)
+		}
+		if isNull {
+			return c.Name + " IS NULL", nil, nil
+		}
+		return c.Name + " IS NOT NULL", nil, nil
+	case QUERY_IN:
+		items := strings.Split(v, ",")
+		if len(items) > maxIn {
+			return "", nil, fmt.Errorf("in takes at most %d values", maxIn)
+		}
+		args := make([]interface{}, len(items))
+		for i, item := range items {
+			arg, err := this.queryValue(c, item)
+			if err != nil {
+				return "", nil, err
+			}
+			args[i] = arg
+		}
+		return c.Name + " IN (" + strings.Repeat("?,", len(items)-1) + "?)",
+			args, nil
+	case QUERY_LIKE:
+		t := this.table.rowType.Field(c.Index).Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.String && t != nullStringType {
+			return "", nil, fmt.Errorf("like can't be used for %s", t)
+		}
+		return c.Name + " LIKE ?", []interface{}{v}, nil
+	}
+
+	sqlOp, ok := queryOps[op]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown operator %s", op)
+	}
+	arg, err := this.queryValue(c, v)
+	if err != nil {
+		return "", nil, err
+	}
+	return c.Name + sqlOp + "?", []interface{}{arg}, nil
+}
+
+// queryValue converts value of query to the SQL value of column c
+func (this *SQLSelector) queryValue(c Column, v string) (interface{}, error) {
+	t := this.table.rowType.Field(c.Index).Type
+	if c.IsAutoCreateTime || c.IsAutoUpdateTime {
+		if tm, ok := parseBindTime(v); ok {
+			return timestampValue(c, t, this.executor().driver(), tm)
+		}
+	}
+
+	field := reflect.New(t).Elem()
+	if err := bindString(field, v); err != nil {
+		return nil, err
+	}
+	return field.Interface(), nil
+}
@@ -0,0 +1,134 @@
+package dbx
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var tUserQueryRules = &QueryRules{
+	Filters: map[string][]string{
+		"id":          nil,
+		"nickname":    {QUERY_EQ, QUERY_LIKE},
+		"update_time": {QUERY_GT, QUERY_LT},
+	},
+	Sortable:     []string{"id", "update_time"},
+	DefaultLimit: 2,
+	MaxLimit:     20,
+}
+
+func TestFromQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+	for i := range TestUsers {
+		_, err := tDatabase.T(USER_TABLE).Insert(&TestUsers[i])
+		assert.Nil(err)
+	}
+
+	values, err := url.ParseQuery("nickname__like=%25ch%25" +
+		"&update_time__gt=2019-01-01&sort=-update_time,id&limit=1&page=2" +
+		"&password=ignored")
+	assert.Nil(err)
+	q, args, err := tDatabase.T(USER_TABLE).Select("id").
+		Filter("userid<>?", "0").FromQuery(values, tUserQueryRules).ToSQL()
+	assert.Nil(err)
+	assert.Equal("SELECT id FROM user WHERE (userid<>?) AND nickname LIKE ? "+
+		"AND update_time>? ORDER BY update_time DESC,id ASC LIMIT 1 OFFSET 1", q)
+	assert.Equal([]interface{}{"0", "%ch%", "2019-01-01"}, args)
+
+	// eschao and chaozh, sorted by update_time desc
+	users := []User{}
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().
+		FromQuery(url.Values{
+			"nickname__like": {"%ch%"},
+			"sort":           {"-update_time"},
+		}, tUserQueryRules).All(&users))
+	assert.Equal(2, len(users))
+	assert.Equal(TestUsers[1].Userid, users[0].Userid)
+	assert.Equal(TestUsers[0].Userid, users[1].Userid)
+
+	// count ignores sorting and pagination
+	n, err := tDatabase.T(USER_TABLE).SelectAll().FromQuery(url.Values{
+		"nickname__like": {"%ch%"}, "sort": {"id"}, "limit": {"1"},
+		"page": {"2"},
+	}, tUserQueryRules).Count()
+	assert.Nil(err)
+	assert.Equal(2, n)
+	_, err = tDatabase.T(USER_TABLE).SelectAll().FromQuery(url.Values{
+		"limit": {"0"}}, tUserQueryRules).Count()
+	assert.NotNil(err)
+
+	// values are typed by fields
+	q, args, err = tDatabase.T(USER_TABLE).SelectAll().
+		FromQuery(url.Values{"id__in": {"1,3"}, "id__null": {"false"}},
+			tUserQueryRules).ToSQL()
+	assert.Nil(err)
+	assert.Equal("SELECT id,userid,nickname,password,update_time FROM user "+
+		"WHERE id IN (?,?) AND id IS NOT NULL LIMIT 2", q)
+	assert.Equal([]interface{}{int64(1), int64(3)}, args)
+
+	// typed selector
+	rows, err := TableOf[User](tDatabase, USER_TABLE).SelectAll().
+		FromQuery(url.Values{"nickname": {"zc"}}, tUserQueryRules).All()
+	assert.Nil(err)
+	assert.Equal(1, len(rows))
+	assert.Equal(TestUsers[2].Userid, rows[0].Userid)
+	n, err = TableOf[User](tDatabase, USER_TABLE).SelectAll().
+		FromQuery(url.Values{"nickname": {"zc"}}, tUserQueryRules).Count()
+	assert.Nil(err)
+	assert.Equal(1, n)
+
+	invalids := []url.Values{
+		{"nickname__gt": {"a"}},
+		{"id__like": {"1"}},
+		{"id": {"one"}},
+		{"id__between": {"1"}},
+		{"id__null": {"maybe"}},
+		{"sort": {"nickname"}},
+		{"limit": {"21"}},
+		{"limit": {"0"}},
+		{"page": {"0"}},
+		{"page": {strconv.Itoa(math.MaxInt)}},
+		{"id__in": {strings.Repeat("1,", defaultMaxIn) + "1"}},
+	}
+	for _, values := range invalids {
+		_, _, err := tDatabase.T(USER_TABLE).SelectAll().
+			FromQuery(values, tUserQueryRules).ToSQL()
+		assert.NotNil(err, values.Encode())
+	}
+
+	// max number of in values
+	rules := *tUserQueryRules
+	rules.MaxIn = 2
+	_, _, err = tDatabase.T(USER_TABLE).SelectAll().
+		FromQuery(url.Values{"id__in": {"1,2"}}, &rules).ToSQL()
+	assert.Nil(err)
+	_, _, err = tDatabase.T(USER_TABLE).SelectAll().
+		FromQuery(url.Values{"id__in": {"1,2,3"}}, &rules).ToSQL()
+	assert.NotNil(err)
+
+	// args given to Filter are never overwritten
+	filterArgs := make([]interface{}, 1, 4)
+	filterArgs[0] = "0"
+	s1 := tDatabase.T(USER_TABLE).SelectAll().Filter("userid<>?", filterArgs...).
+		FromQuery(url.Values{"nickname": {"a"}}, tUserQueryRules)
+	s2 := tDatabase.T(USER_TABLE).SelectAll().Filter("userid<>?", filterArgs...).
+		FromQuery(url.Values{"nickname": {"b"}}, tUserQueryRules)
+	_, args, err = s1.ToSQL()
+	assert.Nil(err)
+	assert.Equal([]interface{}{"0", "a"}, args)
+	_, args, err = s2.ToSQL()
+	assert.Nil(err)
+	assert.Equal([]interface{}{"0", "b"}, args)
+
+	// rules of unknown columns
+	_, _, err = tDatabase.T(USER_TABLE).SelectAll().FromQuery(url.Values{},
+		&QueryRules{Sortable: []string{"no_such_column"}}).ToSQL()
+	assert.NotNil(err)
+}
@@ -0,0 +1,97 @@
+package dbx
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var savepointNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// savepointSQL returns the savepoint statement of driver, op is one of
+// SAVEPOINT, RELEASE and ROLLBACK
+func savepointSQL(driver, op, name string) (string, error) {
+	switch driver {
+	case DRIVER_SQLITE3, DRIVER_MYSQL, DRIVER_POSTGRE:
+		switch op {
+		case "SAVEPOINT":
+			return "SAVEPOINT " + name, nil
+		case "RELEASE":
+			return "RELEASE SAVEPOINT " + name, nil
+		case "ROLLBACK":
+			return "ROLLBACK TO SAVEPOINT " + name, nil
+		}
+		return "", fmt.Errorf("unknown savepoint operation %s", op)
+	}
+	return "", fmt.Errorf("unsupportted driver %s for savepoint", driver)
+}
+
+func (this *Transaction) execSavepoint(op, name string) error {
+	q, err := savepointSQL(this.db.driver, op, name)
+	if err != nil {
+		return err
+	}
+	_, err = this.session().exec("", OP_EXEC, q)
+	return err
+}
+
+// Begin begins a nested transaction with an automatically named savepoint
+func (this *Transaction) Begin() (*Transaction, error) {
+	return this.Savepoint("dbx_sp_" + strconv.Itoa(this.depth+1))
+}
+
+// Savepoint begins a nested transaction with the given savepoint name, its
+// Commit releases the savepoint and its Rollback rolls back to the savepoint
+func (this *Transaction) Savepoint(name string) (*Transaction, error) {
+	if this.tx == nil {
+		return nil, fmt.Errorf("Nil transaction")
+	}
+	if !savepointNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid savepoint name %s", name)
+	}
+
+	if err := this.execSavepoint("SAVEPOINT", name); err != nil {
+		return nil, err
+	}
+	tx := &Transaction{
+		tx: this.tx, db: this.db, savepoint: name, depth: this.depth + 1,
+	}
+	tx.ctx = context.WithValue(this.Context(), txContextKey{}, tx)
+	return tx, nil
+}
+
+func (this *Transaction) releaseSavepoint() error {
+	return this.execSavepoint("RELEASE", this.savepoint)
+}
+
+func (this *Transaction) rollbackSavepoint() error {
+	if err := this.execSavepoint("ROLLBACK", this.savepoint); err != nil {
+		return err
+	}
+	// savepoint is still active after rolling back to it
+	return this.execSavepoint("RELEASE", this.savepoint)
+}
+
+// InTx runs fn in a nested transaction of savepoint, the savepoint is
+// released if fn returns nil, otherwise it's rolled back. If fn panics, the
+// savepoint is rolled back and the panic is propagated
+func (this *Transaction) InTx(fn func(tx *Transaction) error) error {
+	tx, err := this.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
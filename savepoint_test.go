@@ -0,0 +1,97 @@
+package dbx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavepoint(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+
+	tx, err := tDatabase.Begin()
+	assert.Nil(err)
+	_, err = tx.T(USER_TABLE).Insert(&TestUsers[0])
+	assert.Nil(err)
+
+	// rolled back nested transaction
+	sp, err := tx.Savepoint("sp_user")
+	assert.Nil(err)
+	_, err = sp.T(USER_TABLE).Insert(&TestUsers[1])
+	assert.Nil(err)
+	assert.Nil(sp.Rollback())
+
+	// committed nested transaction
+	sp, err = tx.Begin()
+	assert.Nil(err)
+	_, err = sp.T(USER_TABLE).Insert(&TestUsers[2])
+	assert.Nil(err)
+	assert.Nil(sp.Commit())
+	assert.Nil(tx.Commit())
+
+	users := []User{}
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().Asc("id").All(&users))
+	assert.Equal(2, len(users))
+	assert.Equal(TestUsers[0].Userid, users[0].Userid)
+	assert.Equal(TestUsers[2].Userid, users[1].Userid)
+
+	// invalid savepoint name
+	tx, err = tDatabase.Begin()
+	assert.Nil(err)
+	_, err = tx.Savepoint("sp; DROP TABLE user")
+	assert.NotNil(err)
+	assert.Nil(tx.Rollback())
+}
+
+// createUser is an atomic unit of work which can be composed in a caller's
+// transaction
+func createUser(ctx context.Context, user *User, fail bool) error {
+	return tDatabase.InTx(ctx, nil, func(tx *Transaction) error {
+		if _, err := tx.T(USER_TABLE).Insert(user); err != nil {
+			return err
+		}
+		if fail {
+			return fmt.Errorf("failed to create user")
+		}
+		return nil
+	})
+}
+
+func TestNestedInTx(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+
+	assert.Nil(tDatabase.InTx(ctx, nil, func(tx *Transaction) error {
+		assert.Equal(tx, TxFromContext(tx.Context()))
+		assert.Nil(createUser(tx.Context(), &TestUsers[0], false))
+		assert.NotNil(createUser(tx.Context(), &TestUsers[1], true))
+		return tx.InTx(func(sp *Transaction) error {
+			_, err := sp.T(USER_TABLE).Insert(&TestUsers[2])
+			return err
+		})
+	}))
+
+	n, err := tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(2, n)
+	n, err = tDatabase.T(USER_TABLE).Count("userid=?", TestUsers[1].Userid)
+	assert.Nil(err)
+	assert.Equal(0, n)
+
+	// outer rollback discards released savepoints
+	assert.NotNil(tDatabase.InTx(ctx, nil, func(tx *Transaction) error {
+		assert.Nil(createUser(tx.Context(), &TestUsers[1], false))
+		return fmt.Errorf("abort")
+	}))
+	n, err = tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(2, n)
+}
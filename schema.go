@@ -0,0 +1,146 @@
+package dbx
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// TableIndex is an index of table declared by dbx tag of column fields:
+//
+//	Userid   string `db:"userid" sqlite:"TEXT" dbx:"uniqueIndex"`
+//	Nickname string `db:"nickname" sqlite:"TEXT" dbx:"index:idx_name_time"`
+//	Time     int64  `db:"time" sqlite:"INTEGER" dbx:"index:idx_name_time"`
+//
+// The default index name is idx_<table>_<column>, columns of the same index
+// name make a composite index in the order of fields
+type TableIndex struct {
+	Name     string
+	Columns  []string
+	IsUnique bool
+}
+
+// parseIndex adds column col to the index declared by dbx tag options
+func (this *Table) parseIndex(table, col string, opts map[string]string) error {
+	name, isIndex := opts["index"]
+	uniqueName, isUnique := opts["uniqueIndex"]
+	if !isIndex && !isUnique {
+		return nil
+	}
+	if isIndex && isUnique {
+		return fmt.Errorf("column %s has both index and uniqueIndex", col)
+	}
+	if isUnique {
+		name = uniqueName
+	}
+	if name == "" {
+		name = "idx_" + table + "_" + col
+	}
+
+	for i := range this.Indexes {
+		index := &this.Indexes[i]
+		if index.Name == name {
+			if index.IsUnique != isUnique {
+				return fmt.Errorf("index %s is declared as both unique and "+
+					"non-unique", name)
+			}
+			index.Columns = append(index.Columns, col)
+			return nil
+		}
+	}
+	this.Indexes = append(this.Indexes, TableIndex{
+		Name: name, Columns: []string{col}, IsUnique: isUnique,
+	})
+	sort.Slice(this.Indexes, func(i, j int) bool {
+		return this.Indexes[i].Name < this.Indexes[j].Name
+	})
+	return nil
+}
+
+// columnDefs returns column definitions of driver in the order of struct
+// fields, indexes are included for mysql
+func (this *Table) columnDefs(driver string) ([]string, error) {
+	switch driver {
+	case DRIVER_SQLITE3, DRIVER_MYSQL, DRIVER_POSTGRE:
+	default:
+		return nil, fmt.Errorf("unsupportted driver %s", driver)
+	}
+
+	cols := this.orderedColumns()
+	defs := make([]string, 0, len(cols)+len(this.Indexes))
+	for _, c := range cols {
+		def := c.SQL(driver)
+		if def == "" {
+			return nil, fmt.Errorf("%s column of %s table has no definition "+
+				"for %s", c.Name, this.Name, driverName(driver))
+		}
+		defs = append(defs, c.Name+" "+def)
+	}
+
+	// mysql doesn't support CREATE INDEX IF NOT EXISTS, so indexes are
+	// created with table
+	if driver == DRIVER_MYSQL {
+		for _, index := range this.Indexes {
+			kind := "INDEX"
+			if index.IsUnique {
+				kind = "UNIQUE INDEX"
+			}
+			defs = append(defs, kind+" "+index.Name+"("+
+				strings.Join(index.Columns, ",")+")")
+		}
+	}
+	return defs, nil
+}
+
+// IndexSQL returns statements creating indexes of table, indexes of mysql
+// are defined in CreateSQL so it returns nothing for mysql
+func (this *Table) IndexSQL(driver string) []string {
+	if driver == DRIVER_MYSQL {
+		return nil
+	}
+
+	stmts := make([]string, 0, len(this.Indexes))
+	for _, index := range this.Indexes {
+		kind := "CREATE INDEX"
+		if index.IsUnique {
+			kind = "CREATE UNIQUE INDEX"
+		}
+		stmts = append(stmts, kind+" IF NOT EXISTS "+index.Name+" ON "+
+			this.Name+"("+strings.Join(index.Columns, ",")+")")
+	}
+	return stmts
+}
+
+// driverName returns the name of driver used in messages
+func driverName(driver string) string {
+	if driver == DRIVER_SQLITE3 {
+		return "sqlite"
+	}
+	return driver
+}
+
+// DumpSchema writes statements creating all registered tables and their
+// indexes of dialect which is one of DRIVER_* constants. Tables are sorted by
+// name and columns are in the order of struct fields, so the output is the
+// same between runs and can be checked in and diffed. No database is needed
+func (this *Database) DumpSchema(w io.Writer, dialect string) error {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "-- Schema of dbx registered tables for %s\n",
+		driverName(dialect))
+	for _, name := range this.TableNames() {
+		t := this.tables[name]
+		defs, err := t.columnDefs(dialect)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "\nCREATE TABLE IF NOT EXISTS %s (\n\t%s\n);\n", name,
+			strings.Join(defs, ",\n\t"))
+		for _, stmt := range t.IndexSQL(dialect) {
+			b.WriteString(stmt + ";\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,95 @@
+package dbx
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type IndexedUser struct {
+	Id       int64  `db:"id" sqlite:"INTEGER PRIMARY KEY AUTOINCREMENT" mysql:"int NOT NULL PRIMARY KEY AUTO_INCREMENT"`
+	Userid   string `db:"userid" sqlite:"TEXT NOT NULL" mysql:"varchar(32) NOT NULL" dbx:"uniqueIndex"`
+	Nickname string `db:"nickname" sqlite:"TEXT" mysql:"varchar(64)" dbx:"index:idx_name_time"`
+	Time     int64  `db:"time" sqlite:"INTEGER" mysql:"bigint" dbx:"index:idx_name_time"`
+}
+
+func TestDumpSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	db := NewDatabase()
+	assert.Nil(db.RegisterTable("indexed_user", IndexedUser{}))
+	assert.Nil(db.RegisterTable(USER_LOGIN_TABLE, UserLogin{}))
+	assert.Equal([]TableIndex{
+		{Name: "idx_indexed_user_userid", Columns: []string{"userid"},
+			IsUnique: true},
+		{Name: "idx_name_time", Columns: []string{"nickname", "time"}},
+	}, db.tables["indexed_user"].Indexes)
+
+	buf := bytes.Buffer{}
+	assert.Nil(db.DumpSchema(&buf, DRIVER_SQLITE3))
+	schema := buf.String()
+	assert.Contains(schema, `-- Schema of dbx registered tables for sqlite
+
+CREATE TABLE IF NOT EXISTS indexed_user (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	userid TEXT NOT NULL,
+	nickname TEXT,
+	time INTEGER
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_indexed_user_userid ON indexed_user(userid);
+CREATE INDEX IF NOT EXISTS idx_name_time ON indexed_user(nickname,time);
+
+CREATE TABLE IF NOT EXISTS user_login (
+	id INTEGER`)
+
+	// output is the same between runs
+	for i := 0; i < 10; i++ {
+		buf.Reset()
+		assert.Nil(db.DumpSchema(&buf, DRIVER_SQLITE3))
+		assert.Equal(schema, buf.String())
+	}
+
+	buf.Reset()
+	assert.Nil(db.DumpSchema(&buf, DRIVER_MYSQL))
+	assert.Contains(buf.String(), `
+	time bigint,
+	UNIQUE INDEX idx_indexed_user_userid(userid),
+	INDEX idx_name_time(nickname,time)
+);`)
+	assert.NotNil(db.DumpSchema(&buf, DRIVER_POSTGRE))
+	assert.NotNil(db.DumpSchema(&buf, "oracle"))
+
+	// invalid index declarations
+	type BothIndex struct {
+		Id int64 `db:"id" sqlite:"INTEGER" dbx:"index,uniqueIndex"`
+	}
+	assert.NotNil(db.RegisterTable("both_index", BothIndex{}))
+	type MixedIndex struct {
+		A int64 `db:"a" sqlite:"INTEGER" dbx:"index:idx_a_b"`
+		B int64 `db:"b" sqlite:"INTEGER" dbx:"uniqueIndex:idx_a_b"`
+	}
+	assert.NotNil(db.RegisterTable("mixed_index", MixedIndex{}))
+}
+
+func TestCreateTableIndexes(t *testing.T) {
+	assert := assert.New(t)
+
+	db := NewDatabase()
+	assert.Nil(db.OpenSQLite(":memory:"))
+	defer db.Close()
+	db.DB().SetMaxOpenConns(1)
+	assert.Nil(db.RegisterTable("indexed_user", IndexedUser{}))
+	assert.Nil(db.CreateTables())
+	// indexes are created if not existing
+	assert.Nil(db.CreateTable("indexed_user"))
+
+	tables, err := db.Introspect("indexed_user")
+	assert.Nil(err)
+	assert.True(tables[0].Column("userid").IsUnique)
+
+	_, err = db.T("indexed_user").Insert(&IndexedUser{Userid: "u1"})
+	assert.Nil(err)
+	_, err = db.T("indexed_user").Insert(&IndexedUser{Userid: "u1"})
+	assert.NotNil(err)
+}
@@ -0,0 +1,124 @@
+package dbx
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// SlowQuery describes a statement executed slower than the threshold
+type SlowQuery struct {
+	QueryEvent
+	// Caller is the file:line of the first caller outside dbx
+	Caller string
+	// Plan is rows of EXPLAIN (MySQL) or EXPLAIN QUERY PLAN (SQLite) of the
+	// statement, each row is formatted as "column=value ...". It's nil if
+	// explain is disabled or fails
+	Plan []string
+}
+
+// SlowQueryOptions defines options of slow query detection
+type SlowQueryOptions struct {
+	// Threshold is the min duration of a slow query
+	Threshold time.Duration
+	// Handler is called with every slow query
+	Handler func(q *SlowQuery)
+	// Explain captures the query plan of slow select, count, update and
+	// delete statements by running EXPLAIN in the same connection
+	Explain bool
+}
+
+// SetSlowQuery enables reporting statements slower than opts.Threshold to
+// opts.Handler, nil disables it
+func (this *Database) SetSlowQuery(opts *SlowQueryOptions) {
+	if opts != nil && opts.Handler == nil {
+		opts = nil
+	}
+	this.slowQuery = opts
+}
+
+var dbxPkgPrefix = reflect.TypeOf(Database{}).PkgPath() + "."
+
+// callerOutside returns file:line of the first caller outside dbx package
+func callerOutside() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, dbxPkgPrefix) ||
+			strings.HasSuffix(f.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// checkSlow reports ev to slow query handler of database if it's slow
+func (this *sqlSession) checkSlow(ev *QueryEvent) {
+	if this.database == nil || this.database.slowQuery == nil {
+		return
+	}
+	opts := this.database.slowQuery
+	if ev.Duration < opts.Threshold {
+		return
+	}
+
+	q := &SlowQuery{QueryEvent: *ev, Caller: callerOutside()}
+	if opts.Explain && ev.Err == nil {
+		switch ev.Op {
+		case OP_SELECT, OP_COUNT, OP_UPDATE, OP_DELETE:
+			q.Plan, _ = this.explain(ev.SQL, ev.Args)
+		}
+	}
+	opts.Handler(q)
+}
+
+// explain returns the query plan of statement, the EXPLAIN statement itself
+// isn't logged
+func (this *sqlSession) explain(q string, args []interface{}) ([]string,
+	error) {
+	switch this.database.driver {
+	case DRIVER_SQLITE3:
+		q = "EXPLAIN QUERY PLAN " + q
+	case DRIVER_MYSQL:
+		q = "EXPLAIN " + q
+	default:
+		return nil, fmt.Errorf("unsupportted driver %s for explain",
+			this.database.driver)
+	}
+
+	rs, err := this.conn().QueryContext(this.context(), q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+
+	cols, err := rs.Columns()
+	if err != nil {
+		return nil, err
+	}
+	vals := make([]sql.NullString, len(cols))
+	refs := make([]interface{}, len(cols))
+	for i := range vals {
+		refs[i] = &vals[i]
+	}
+
+	plan := []string{}
+	for rs.Next() {
+		if err := rs.Scan(refs...); err != nil {
+			return nil, err
+		}
+		fields := make([]string, len(cols))
+		for i, c := range cols {
+			fields[i] = c + "=" + vals[i].String
+		}
+		plan = append(plan, strings.Join(fields, " "))
+	}
+	return plan, rs.Err()
+}
@@ -0,0 +1,60 @@
+package dbx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+	_, err := tDatabase.T(USER_TABLE).Insert(&TestUsers[0])
+	assert.Nil(err)
+
+	slows := []*SlowQuery{}
+	tDatabase.SetSlowQuery(&SlowQueryOptions{
+		Threshold: time.Nanosecond,
+		Handler: func(q *SlowQuery) {
+			slows = append(slows, q)
+		},
+		Explain: true,
+	})
+	defer tDatabase.SetSlowQuery(nil)
+
+	user := User{}
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().
+		Filter("userid=?", TestUsers[0].Userid).One(&user))
+	assert.Equal(1, len(slows))
+	assert.Equal(OP_SELECT, slows[0].Op)
+	assert.Equal(USER_TABLE, slows[0].Table)
+	assert.Equal([]interface{}{TestUsers[0].Userid}, slows[0].Args)
+	assert.True(slows[0].Duration > 0)
+	assert.True(strings.Contains(slows[0].Caller, "slowquery_test.go:"),
+		slows[0].Caller)
+	assert.NotEmpty(slows[0].Plan)
+
+	// insert isn't explained
+	slows = slows[:0]
+	_, err = tDatabase.T(USER_TABLE).Insert(&TestUsers[1])
+	assert.Nil(err)
+	assert.Equal(1, len(slows))
+	assert.Equal(OP_INSERT, slows[0].Op)
+	assert.Nil(slows[0].Plan)
+
+	// queries faster than threshold aren't reported
+	slows = slows[:0]
+	tDatabase.SetSlowQuery(&SlowQueryOptions{
+		Threshold: time.Hour,
+		Handler: func(q *SlowQuery) {
+			slows = append(slows, q)
+		},
+	})
+	_, err = tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(0, len(slows))
+}
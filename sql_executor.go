@@ -14,13 +14,25 @@ type sqlFilter struct {
 
 type tableGetter func(string) *Table
 
+// Querier is implemented by Database and Transaction to get executor of table
+type Querier interface {
+	T(name string) *SQLExecutor
+}
+
 // SQLExecutor
 type SQLExecutor struct {
-	table       *Table
-	db          *sql.DB
-	tx          *sql.Tx
-	err         error
-	tableGetter tableGetter
+	sqlSession
+	table *Table
+	err   error
+	scope int
+}
+
+// WithLogger returns an executor whose statements are logged by the given
+// logger instead of logger of database
+func (this *SQLExecutor) WithLogger(logger Logger) *SQLExecutor {
+	e := *this
+	e.logger = logger
+	return &e
 }
 
 // Insert inserts given row to table
@@ -29,42 +41,72 @@ func (this *SQLExecutor) Insert(row interface{}) (sql.Result, error) {
 		return nil, this.err
 	}
 
+	rowVal, err := this.table.rowValue(row)
+	if err != nil {
+		return nil, err
+	}
+	if err := this.stampInsert(rowVal); err != nil {
+		return nil, err
+	}
+	if h, ok := row.(BeforeInserter); ok {
+		if err := h.BeforeInsert(this); err != nil {
+			return nil, err
+		}
+	}
+
+	q, refs, err := this.insertSQL(rowVal)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := this.exec(this.table.Name, OP_INSERT, q, refs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if h, ok := row.(AfterInserter); ok {
+		if err := h.AfterInsert(this); err != nil {
+			return rs, err
+		}
+	}
+	return rs, nil
+}
+
+// InsertSQL returns the insert statement of given row and its arguments
+// without executing it, hooks and timestamps are not applied
+func (this *SQLExecutor) InsertSQL(row interface{}) (string, []interface{},
+	error) {
+	if this.err != nil {
+		return "", nil, this.err
+	}
+	rowVal, err := this.table.rowValue(row)
+	if err != nil {
+		return "", nil, err
+	}
+	return this.insertSQL(rowVal)
+}
+
+func (this *SQLExecutor) insertSQL(rowVal reflect.Value) (string,
+	[]interface{}, error) {
 	cols := ""
 	vals := ""
 	refs := make([]interface{}, 0, len(this.table.Columns))
-	rowVal := reflect.ValueOf(row).Elem()
 
-	for k, v := range this.table.Columns {
-		if !v.IsAutoIncrement {
-			cols += k + ","
+	for _, c := range this.table.orderedColumns() {
+		if !c.IsAutoIncrement {
+			cols += c.Name + ","
 			vals += "?,"
-			refs = append(refs, rowVal.Field(v.Index).Interface())
+			refs = append(refs, rowVal.Field(c.Index).Interface())
 		}
 	}
 
 	if cols == "" {
-		return nil, fmt.Errorf("table doesn't have columns")
+		return "", nil, fmt.Errorf("table doesn't have columns")
 	}
 
 	cols = cols[:len(cols)-1]
 	vals = vals[:len(vals)-1]
 	q := "INSERT INTO " + this.table.Name + "(" + cols + ") VALUES(" + vals + ")"
-	if dbLogger != nil {
-		dbLogger(q)
-	}
-
-	var stmt *sql.Stmt
-	var err error
-	if this.tx != nil {
-		stmt, err = this.tx.Prepare(q)
-	} else {
-		stmt, err = this.db.Prepare(q)
-	}
-	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
-	return stmt.Exec(refs...)
+	return q, refs, nil
 }
 
 // CountAll counts all rows of table
@@ -74,33 +116,10 @@ func (this *SQLExecutor) CountAll() (int, error) {
 	}
 
 	q := "SELECT COUNT(*) as count FROM " + this.table.Name
-	if dbLogger != nil {
-		dbLogger(q)
-	}
-
-	var rs *sql.Rows
-	var err error
-	if this.tx != nil {
-		rs, err = this.tx.Query(q)
-	} else {
-		rs, err = this.db.Query(q)
+	if cond := this.table.scopeSQL(this.scope); cond != "" {
+		q += " WHERE " + cond
 	}
-
-	if err == sql.ErrNoRows {
-		return 0, nil
-	}
-	if err != nil {
-		return 0, err
-	}
-	defer rs.Close()
-
-	var count = 0
-	if rs.Next() {
-		if err := rs.Scan(&count); err != nil {
-			return 0, err
-		}
-	}
-	return count, nil
+	return this.count(q)
 }
 
 // Count counts rows by given filter
@@ -110,125 +129,201 @@ func (this *SQLExecutor) Count(where string, args ...interface{}) (int, error) {
 	}
 
 	q := "SELECT COUNT(*) as count FROM " + this.table.Name
+	where = scopeWhere(where, this.table.scopeSQL(this.scope))
 	if where != "" {
 		q += " WHERE " + where
 	}
-	if dbLogger != nil {
-		dbLogger(q)
-	}
-
-	var rs *sql.Rows
-	var err error
-	if this.tx != nil {
-		rs, err = this.tx.Query(q, args...)
-	} else {
-		rs, err = this.db.Query(q, args...)
-	}
+	return this.count(q, args...)
+}
 
+func (this *SQLExecutor) count(q string, args ...interface{}) (int, error) {
+	var count = 0
+	err := this.query(this.table.Name, OP_COUNT, q, args,
+		func(rs *sql.Rows) (int64, error) {
+			if rs.Next() {
+				return 1, rs.Scan(&count)
+			}
+			return 0, nil
+		})
 	if err == sql.ErrNoRows {
 		return 0, nil
 	}
 	if err != nil {
 		return 0, err
 	}
-	defer rs.Close()
-
-	var count = 0
-	if rs.Next() {
-		if err := rs.Scan(&count); err != nil {
-			return 0, err
-		}
-	}
 	return count, nil
 }
 
 // SelectAll selects all columns from table
 func (this *SQLExecutor) SelectAll() *SQLSelector {
 	return &SQLSelector{
-		table: this.table, db: this.db, tx: this.tx, err: this.err,
-		tableGetter: this.tableGetter,
-		columns:     this.table.ColumnNames(),
-		filter:      sqlFilter{args: []interface{}{}},
-		sort:        sqlSort{columns: []string{}},
+		sqlSession: this.sqlSession, table: this.table, err: this.err,
+		nullAsZero: this.database != nil && this.database.nullAsZero,
+		scope:      this.scope,
+		columns:    this.table.ColumnNames(),
+		filter:     sqlFilter{args: []interface{}{}},
+		sort:       sqlSort{columns: []string{}},
 	}
 }
 
 // Select selects the given columns from table
 func (this *SQLExecutor) Select(cols ...string) *SQLSelector {
 	return &SQLSelector{
-		table: this.table, db: this.db, tx: this.tx, err: this.err, columns: cols,
-		tableGetter: this.tableGetter,
-		filter:      sqlFilter{args: []interface{}{}},
-		sort:        sqlSort{columns: []string{}},
+		sqlSession: this.sqlSession, table: this.table, err: this.err,
+		columns:    cols,
+		nullAsZero: this.database != nil && this.database.nullAsZero,
+		scope:      this.scope,
+		filter:     sqlFilter{args: []interface{}{}},
+		sort:       sqlSort{columns: []string{}},
 	}
 }
 
-// Delete deletes rows by given filter
+// Delete deletes rows by given filter, rows are marked as deleted instead if
+// table has a soft delete column
 func (this *SQLExecutor) Delete(where string, args ...interface{}) error {
 	if this.err != nil {
 		return this.err
 	}
 
+	q, args, err := this.deleteSQL(where, args...)
+	if err != nil {
+		return err
+	}
+	_, err = this.exec(this.table.Name, OP_DELETE, q, args...)
+	return err
+}
+
+// DeleteSQL returns the delete statement by given filter and its arguments
+// without executing it, it's an update statement if rows are soft deleted
+func (this *SQLExecutor) DeleteSQL(where string, args ...interface{}) (
+	string, []interface{}, error) {
+	if this.err != nil {
+		return "", nil, this.err
+	}
+	return this.deleteSQL(where, args...)
+}
+
+func (this *SQLExecutor) deleteSQL(where string, args ...interface{}) (
+	string, []interface{}, error) {
+	col := this.table.SoftDeleteColumn()
+	if col != "" && this.scope != scopeUnscoped {
+		return this.softDeleteSQL(col, where, args...)
+	}
+
 	q := "DELETE FROM " + this.table.Name
 	if where != "" {
 		q += " WHERE " + where
 	}
-	if dbLogger != nil {
-		dbLogger(q)
+	return q, args, nil
+}
+
+// DeleteRow deletes the given row by its primary key
+func (this *SQLExecutor) DeleteRow(row interface{}) error {
+	if this.err != nil {
+		return this.err
 	}
 
-	var err error
-	if this.tx != nil {
-		_, err = this.tx.Exec(q, args...)
-	} else {
-		_, err = this.db.Exec(q, args...)
+	pk := this.table.PrimaryKey()
+	if pk == "" {
+		return fmt.Errorf("%s table has no primary key", this.table.Name)
 	}
-	return err
+	rowVal, err := this.table.rowValue(row)
+	if err != nil {
+		return err
+	}
+
+	if h, ok := row.(BeforeDeleter); ok {
+		if err := h.BeforeDelete(this); err != nil {
+			return err
+		}
+	}
+
+	key := rowVal.Field(this.table.Columns[pk].Index).Interface()
+	if err := this.Delete(pk+"=?", key); err != nil {
+		return err
+	}
+
+	if h, ok := row.(AfterDeleter); ok {
+		return h.AfterDelete(this)
+	}
+	return nil
 }
 
 // Replace replaces with given row
 func (this *SQLExecutor) Replace(row interface{}) (sql.Result, error) {
+	if this.err != nil {
+		return nil, this.err
+	}
+
+	rowVal, err := this.table.rowValue(row)
+	if err != nil {
+		return nil, err
+	}
+	if err := this.stampInsert(rowVal); err != nil {
+		return nil, err
+	}
+	if h, ok := row.(BeforeInserter); ok {
+		if err := h.BeforeInsert(this); err != nil {
+			return nil, err
+		}
+	}
+
+	q, refs, err := this.replaceSQL(rowVal)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := this.exec(this.table.Name, OP_REPLACE, q, refs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if h, ok := row.(AfterInserter); ok {
+		if err := h.AfterInsert(this); err != nil {
+			return rs, err
+		}
+	}
+	return rs, nil
+}
+
+// ReplaceSQL returns the replace statement of given row and its arguments
+// without executing it, hooks and timestamps are not applied
+func (this *SQLExecutor) ReplaceSQL(row interface{}) (string, []interface{},
+	error) {
+	if this.err != nil {
+		return "", nil, this.err
+	}
+	rowVal, err := this.table.rowValue(row)
+	if err != nil {
+		return "", nil, err
+	}
+	return this.replaceSQL(rowVal)
+}
+
+func (this *SQLExecutor) replaceSQL(rowVal reflect.Value) (string,
+	[]interface{}, error) {
 	cols := ""
 	vals := ""
 	size := len(this.table.Columns)
 	refs := make([]interface{}, size, size)
-	i := 0
-	rowVal := reflect.ValueOf(row).Elem()
-	for k, v := range this.table.Columns {
-		cols += k + ","
+	for i, c := range this.table.orderedColumns() {
+		cols += c.Name + ","
 		vals += "?,"
-		refs[i] = rowVal.Field(v.Index).Addr().Interface()
-		i++
+		refs[i] = rowVal.Field(c.Index).Addr().Interface()
 	}
 
 	if cols == "" {
-		return nil, fmt.Errorf("table doesn't have columns")
+		return "", nil, fmt.Errorf("table doesn't have columns")
 	}
 	cols = cols[:len(cols)-1]
 	vals = vals[:len(vals)-1]
 	q := "REPLACE INTO " + this.table.Name + "(" + cols + ") VALUES(" + vals + ")"
-	if dbLogger != nil {
-		dbLogger(q)
-	}
-
-	var stmt *sql.Stmt
-	var err error
-	if this.tx != nil {
-		stmt, err = this.tx.Prepare(q)
-	} else {
-		stmt, err = this.db.Prepare(q)
-	}
-	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
-	return stmt.Exec(refs...)
+	return q, refs, nil
 }
 
 // Update updates row by given filter
 func (this *SQLExecutor) Update(where string, args ...interface{}) *SQLUpdater {
 	return &SQLUpdater{
-		table: this.table, db: this.db, tx: this.tx, err: this.err,
+		sqlSession: this.sqlSession, table: this.table, err: this.err,
 		filter: sqlFilter{where: where, args: args},
 	}
 }
@@ -1,6 +1,7 @@
 package dbx
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -97,3 +98,56 @@ func TestInsertCountDeleteWithTx(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(n, 0)
 }
+
+func TestInsertDeleteToSQL(t *testing.T) {
+	assert := assert.New(t)
+
+	user := TestUsers[0]
+	q, args, err := tDatabase.T(USER_TABLE).InsertSQL(&user)
+	assert.Nil(err)
+	assert.True(strings.HasPrefix(q, "INSERT INTO user("))
+	cols := strings.Split(q[len("INSERT INTO user("):strings.Index(q, ")")], ",")
+	assert.NotContains(cols, "id")
+	assert.Equal(4, len(args))
+	assert.Contains(args, user.Userid)
+
+	q, args, err = tDatabase.T(USER_TABLE).ReplaceSQL(&user)
+	assert.Nil(err)
+	assert.True(strings.HasPrefix(q, "REPLACE INTO user("))
+	assert.Equal(5, len(args))
+
+	q, args, err = tDatabase.T(USER_TABLE).DeleteSQL("userid=?", "u1")
+	assert.Nil(err)
+	assert.Equal("DELETE FROM user WHERE userid=?", q)
+	assert.Equal([]interface{}{"u1"}, args)
+
+	// soft delete
+	q, args, err = tDatabase.T(USER_PROFILE_TABLE).DeleteSQL("id=?", 1)
+	assert.Nil(err)
+	assert.Equal("UPDATE user_profile SET deleted_at=? WHERE (id=?) AND "+
+		"user_profile.deleted_at IS NULL", q)
+	assert.Equal(2, len(args))
+	assert.Equal(1, args[1])
+}
+
+func TestRowArguments(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+
+	e := tDatabase.T(USER_TABLE)
+	_, err := e.Insert(TestUsers[0])
+	assert.NotNil(err)
+	_, err = e.Insert((*User)(nil))
+	assert.NotNil(err)
+	_, err = e.Replace(&UserLogin{})
+	assert.NotNil(err)
+	_, _, err = e.InsertSQL(User{})
+	assert.NotNil(err)
+	assert.NotNil(e.DeleteRow(User{}))
+	assert.NotNil(e.SelectAll().One(User{}))
+	assert.NotNil(e.SelectAll().All(&[]UserLogin{}))
+	_, err = e.Update("id=?", 1).Value(User{})
+	assert.NotNil(err)
+}
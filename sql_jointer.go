@@ -69,6 +69,20 @@ func (this *SQLJointer) Desc(cols ...string) *SQLJointer {
 	return this
 }
 
+// OrderBy sorts given columns by their own orders, a column prefixed with
+// "-" is sorted by desc
+func (this *SQLJointer) OrderBy(cols ...string) *SQLJointer {
+	this.selector.OrderBy(cols...)
+	return this
+}
+
+// NullAsZero scans NULL columns as the zero value of struct fields instead
+// of failing
+func (this *SQLJointer) NullAsZero() *SQLJointer {
+	this.selector.nullAsZero = true
+	return this
+}
+
 // Limit sets limit of query
 func (this *SQLJointer) Limit(n int) *SQLJointer {
 	this.selector.limit = n
@@ -195,31 +209,42 @@ func (this *SQLJointer) buildJoinSQL() (string, *[][]int, int, error) {
 				leftmost + "." + join.onLeft + "=" + join.table + "." + join.onRight
 		}
 
+		if selector.scope != scopeUnscoped {
+			if cond := table.scopeSQL(scopeDefault); cond != "" {
+				joinSQL += " AND " + cond
+			}
+		}
 		if join.where != "" {
 			joinSQL += " " + join.where
 		}
 	}
 
 	sql := "SELECT " + cols + " FROM " + joinSQL
-	if selector.filter.where != "" {
-		sql += " WHERE " + selector.filter.where
-	}
-	if len(selector.sort.columns) > 0 {
-		sql += " ORDER BY " + selector.sort.buildSQL(leftmost) + " " +
-			selector.sort.op
+	where := scopeWhere(selector.filter.where, selector.table.scopeSQL(selector.scope))
+	if where != "" {
+		sql += " WHERE " + where
 	}
+	sql += selector.sort.buildSQL(leftmost)
 	if selector.limit > 0 {
 		sql += " LIMIT " + strconv.Itoa(selector.limit)
 	}
 	if selector.offset > 0 {
 		sql += " OFFSET " + strconv.Itoa(selector.offset)
 	}
+	return sql, &indexes, count, nil
+}
 
-	if dbLogger != nil {
-		dbLogger(sql)
+// ToSQL returns the query and its arguments without executing it
+func (this *SQLJointer) ToSQL() (string, []interface{}, error) {
+	if this.selector.err != nil {
+		return "", nil, this.selector.err
 	}
 
-	return sql, &indexes, count, nil
+	q, _, _, err := this.buildJoinSQL()
+	if err != nil {
+		return "", nil, err
+	}
+	return q, this.selector.filter.args, nil
 }
 
 func (this *SQLJointer) Run() (*sql.Rows, error) {
@@ -233,13 +258,13 @@ func (this *SQLJointer) Run() (*sql.Rows, error) {
 		return nil, err
 	}
 
-	if selector.tx != nil {
-		return selector.tx.Query(q, selector.filter.args...)
-	} else {
-		return selector.db.Query(q, selector.filter.args...)
-	}
+	return selector.rows(selector.table.Name, OP_SELECT, q,
+		selector.filter.args)
 }
 
+// One selects one row of the joined tables into given rows. A row argument
+// can be the address of a struct pointer, it will be set to nil if all of
+// its columns are NULL, e.g. no matched row in a LEFT JOIN
 func (this *SQLJointer) One(rows ...interface{}) error {
 	selector := this.selector
 	if selector.err != nil {
@@ -256,24 +281,48 @@ func (this *SQLJointer) One(rows ...interface{}) error {
 
 	j := 0
 	refs := make([]interface{}, n, n)
+	rowVals := make([]reflect.Value, len(rows), len(rows))
+	targets := make([]reflect.Value, len(rows), len(rows))
+	scanners := make([]*rowScanner, len(rows), len(rows))
 	for i, row := range rows {
-		rowVal := reflect.ValueOf(row).Elem()
-		for _, c := range (*indexes)[i] {
-			refs[j] = rowVal.Field(c).Addr().Interface()
-			j++
+		rowVal := reflect.ValueOf(row)
+		if rowVal.Kind() != reflect.Ptr {
+			return fmt.Errorf("rows argument must be a struct address")
 		}
+		rowVal = rowVal.Elem()
+		target := rowVal
+		if rowVal.Kind() == reflect.Ptr {
+			target = reflect.New(rowVal.Type().Elem()).Elem()
+		}
+		rowVals[i] = rowVal
+		targets[i] = target
+		scanners[i] = newRowScanner(target, (*indexes)[i], selector.nullAsZero)
+		j += scanners[i].refs(refs[j:])
 	}
 
-	var rs *sql.Row
-	if selector.tx != nil {
-		rs = selector.tx.QueryRow(q, selector.filter.args...)
-	} else {
-		rs = selector.db.QueryRow(q, selector.filter.args...)
+	err = selector.queryRow(selector.table.Name, OP_SELECT, q,
+		selector.filter.args, refs...)
+	if err != nil {
+		return err
 	}
-
-	return rs.Scan(refs...)
+	for i, s := range scanners {
+		if err := setScannedRow(rowVals[i], targets[i], s); err != nil {
+			return err
+		}
+	}
+	for i, s := range scanners {
+		if !s.absent() {
+			if err := afterFind(this.executor(i), targets[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
+// All selects all rows of the joined tables into given slice addresses. The
+// slice element can be a struct pointer, it will be nil if all of its columns
+// are NULL, e.g. no matched row in a LEFT JOIN
 func (this *SQLJointer) All(rows ...interface{}) error {
 	selector := this.selector
 	if selector.err != nil {
@@ -304,43 +353,88 @@ func (this *SQLJointer) All(rows ...interface{}) error {
 		return fmt.Errorf("not enough rows arguments")
 	}
 
-	var rs *sql.Rows
-	if selector.tx != nil {
-		rs, err = selector.tx.Query(q, selector.filter.args...)
-	} else {
-		rs, err = selector.db.Query(q, selector.filter.args...)
-	}
+	refs := make([]interface{}, n, n)
+	rowsPt := make([]reflect.Value, size, size)
+	targets := make([]reflect.Value, size, size)
+	scanners := make([]*rowScanner, size, size)
+
+	found := make([][]int, size, size)
+	err = selector.query(selector.table.Name, OP_SELECT, q, selector.filter.args,
+		func(rs *sql.Rows) (int64, error) {
+			count := int64(0)
+			for rs.Next() {
+				k := 0
+				for i, t := range rowTypes {
+					p := reflect.New(t)
+					target := p.Elem()
+					if t.Kind() == reflect.Ptr {
+						target = reflect.New(t.Elem()).Elem()
+					}
+					rowsPt[i] = p
+					targets[i] = target
+					scanners[i] = newRowScanner(target, (*indexes)[i],
+						selector.nullAsZero)
+					k += scanners[i].refs(refs[k:])
+				}
+
+				if err := rs.Scan(refs...); err != nil {
+					return count, err
+				}
+
+				for i, s := range scanners {
+					err := setScannedRow(rowsPt[i].Elem(), targets[i], s)
+					if err != nil {
+						return count, err
+					}
+					sliceVals[i] = reflect.Append(sliceVals[i], rowsPt[i].Elem())
+					if !s.absent() {
+						found[i] = append(found[i], sliceVals[i].Len()-1)
+					}
+				}
+				count++
+			}
+			return count, nil
+		})
 	if err != nil {
 		return err
 	}
-	defer rs.Close()
 
-	refs := make([]interface{}, n, n)
-	rowsPt := make([]reflect.Value, size, size)
-
-	for rs.Next() {
-		k := 0
-		for i, t := range rowTypes {
-			p := reflect.New(t)
-			row := p.Elem()
-			for _, j := range (*indexes)[i] {
-				refs[k] = row.Field(j).Addr().Interface()
-				k++
-			}
-			rowsPt[i] = p
+	for i, _ := range rowsVals {
+		rowsVals[i].Elem().Set(sliceVals[i]) //.Slice(0, i))
+	}
+	for i := range found {
+		scanned := make([]reflect.Value, len(found[i]), len(found[i]))
+		for k, j := range found[i] {
+			scanned[k] = reflect.Indirect(sliceVals[i].Index(j))
 		}
-
-		if err := rs.Scan(refs...); err != nil {
+		if err := afterFind(this.executor(i), scanned...); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		for i, _ := range sliceVals {
-			sliceVals[i] = reflect.Append(sliceVals[i], rowsPt[i].Elem())
-		}
+// executor returns executor of the i-th table in join, 0 is the leftmost
+func (this *SQLJointer) executor(i int) *SQLExecutor {
+	e := this.selector.executor()
+	if i > 0 {
+		e.table = this.selector.tableGetter(this.joins[i-1].table)
 	}
+	return e
+}
 
-	for i, _ := range rowsVals {
-		rowsVals[i].Elem().Set(sliceVals[i]) //.Slice(0, i))
+// setScannedRow sets the scanned target to row, row is set to its zero value
+// if all scanned columns are NULL
+func setScannedRow(row, target reflect.Value, s *rowScanner) error {
+	if s.absent() {
+		row.Set(reflect.Zero(row.Type()))
+		return nil
+	}
+	if err := s.err(); err != nil {
+		return err
+	}
+	if row.Kind() == reflect.Ptr {
+		row.Set(target.Addr())
 	}
 	return nil
 }
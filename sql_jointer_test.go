@@ -48,3 +48,27 @@ func TestJoin(t *testing.T) {
 	assert.Equal(len(users), 3)
 	assert.Equal(len(userLogins), 3)
 }
+
+func TestJoinToSQL(t *testing.T) {
+	assert := assert.New(t)
+
+	q, args, err := tDatabase.T(USER_TABLE).Select("id").
+		LeftJoin(USER_LOGIN_TABLE, "userid", "userid").Select("last_ip").
+		Filter("user.userid=?", "u1").ToSQL()
+	assert.Nil(err)
+	assert.Equal("SELECT user.id,user_login.last_ip FROM user LEFT JOIN "+
+		"user_login ON user.userid=user_login.userid WHERE user.userid=?", q)
+	assert.Equal([]interface{}{"u1"}, args)
+
+	q, _, err = tDatabase.T(USER_TABLE).Select("id").
+		LeftJoin(USER_LOGIN_TABLE, "userid", "userid").Select("last_ip").
+		OrderBy("-id").ToSQL()
+	assert.Nil(err)
+	assert.Equal("SELECT user.id,user_login.last_ip FROM user LEFT JOIN "+
+		"user_login ON user.userid=user_login.userid ORDER BY user.id DESC", q)
+
+	_, _, err = tDatabase.T(USER_TABLE).Select("id").
+		LeftJoin(USER_LOGIN_TABLE, "userid", "userid").Select("no_such_column").
+		ToSQL()
+	assert.NotNil(err)
+}
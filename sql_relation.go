@@ -0,0 +1,265 @@
+package dbx
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	RELATION_HAS_ONE    = "hasOne"
+	RELATION_HAS_MANY   = "hasMany"
+	RELATION_BELONGS_TO = "belongsTo"
+)
+
+// max number of keys in one IN (...) query of preloading
+const maxPreloadKeys = 500
+
+// Relation defines a relationship from a table to another table. For hasOne
+// and hasMany, ForeignKey is the column of related table which references the
+// References column (default primary key) of this table. For belongsTo,
+// ForeignKey is the column of this table which references the References
+// column (default primary key) of related table
+type Relation struct {
+	Name       string
+	Kind       string
+	Table      string
+	ForeignKey string
+	References string
+	Index      int
+}
+
+// parseRelation parses relation definition from dbx tag of struct field:
+//
+//	Logins []UserLogin `dbx:"hasMany:user_login,foreignKey:userid,references:userid"`
+//
+// it returns nil if the field doesn't define a relation
+func parseRelation(f reflect.StructField, index int, opts map[string]string) (
+	*Relation, error) {
+	rel := Relation{
+		Name:       f.Name,
+		ForeignKey: opts["foreignKey"],
+		References: opts["references"],
+		Index:      index,
+	}
+	for _, kind := range []string{
+		RELATION_HAS_ONE, RELATION_HAS_MANY, RELATION_BELONGS_TO,
+	} {
+		if table, ok := opts[kind]; ok {
+			if rel.Kind != "" {
+				return nil, fmt.Errorf("field %s has more than one relation", f.Name)
+			}
+			rel.Kind = kind
+			rel.Table = table
+		}
+	}
+
+	if rel.Kind == "" {
+		return nil, nil
+	}
+	if err := rel.validate(f.Type); err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+func (this *Relation) validate(t reflect.Type) error {
+	if this.Table == "" {
+		return fmt.Errorf("relation %s has no related table", this.Name)
+	}
+	if this.ForeignKey == "" {
+		return fmt.Errorf("relation %s has no foreign key", this.Name)
+	}
+
+	switch this.Kind {
+	case RELATION_HAS_MANY:
+		if t.Kind() != reflect.Slice {
+			return fmt.Errorf("hasMany relation %s must be a slice", this.Name)
+		}
+		t = t.Elem()
+	case RELATION_HAS_ONE, RELATION_BELONGS_TO:
+	default:
+		return fmt.Errorf("unknown kind %s of relation %s", this.Kind, this.Name)
+	}
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("relation %s must be a struct type", this.Name)
+	}
+	return nil
+}
+
+// Preload loads the given relations of selected rows, one query per relation
+// is executed after the main query
+func (this *SQLSelector) Preload(relations ...string) *SQLSelector {
+	this.preloads = append(this.preloads, relations...)
+	return this
+}
+
+// preload loads relations into the given parent rows, every row must be an
+// addressable struct value
+func (this *SQLSelector) preload(rows []reflect.Value) error {
+	for _, name := range this.preloads {
+		rel, ok := this.table.Relations[name]
+		if !ok {
+			return fmt.Errorf("%s table has no relation %s", this.table.Name, name)
+		}
+		if err := this.preloadRelation(rel, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *SQLSelector) preloadRelation(rel Relation, rows []reflect.Value) error {
+	related := this.tableGetter(rel.Table)
+	if related == nil || related.Name == "" {
+		return fmt.Errorf("related table %s is not registered", rel.Table)
+	}
+
+	// resolve the columns to be matched
+	parentCol, childCol := rel.References, rel.ForeignKey
+	if rel.Kind == RELATION_BELONGS_TO {
+		parentCol, childCol = rel.ForeignKey, rel.References
+		if childCol == "" {
+			childCol = related.PrimaryKey()
+		}
+	} else if parentCol == "" {
+		parentCol = this.table.PrimaryKey()
+	}
+
+	pc, ok := this.table.Columns[parentCol]
+	if !ok {
+		return fmt.Errorf("%s table has no column %s", this.table.Name, parentCol)
+	}
+	cc, ok := related.Columns[childCol]
+	if !ok {
+		return fmt.Errorf("%s table has no column %s", rel.Table, childCol)
+	}
+
+	// collect distinct keys of parent rows
+	keys := []interface{}{}
+	seen := map[string]bool{}
+	for _, row := range rows {
+		v, k, err := relationKey(row.Field(pc.Index))
+		if err != nil {
+			return err
+		}
+		if v != nil && !seen[k] {
+			seen[k] = true
+			keys = append(keys, v)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	fieldType := rows[0].Field(rel.Index).Type()
+	elemType := fieldType
+	if rel.Kind == RELATION_HAS_MANY {
+		elemType = elemType.Elem()
+	}
+	isPtr := elemType.Kind() == reflect.Ptr
+	childType := elemType
+	if isPtr {
+		childType = childType.Elem()
+	}
+
+	// query related rows and group them by key
+	groups := map[string][]reflect.Value{}
+	for start := 0; start < len(keys); start += maxPreloadKeys {
+		end := start + maxPreloadKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		children := reflect.New(reflect.SliceOf(childType))
+		executor := this.executor()
+		executor.table = related
+		if executor.scope != scopeUnscoped {
+			executor.scope = scopeDefault
+		}
+		where := childCol + " IN (?" + strings.Repeat(",?", end-start-1) + ")"
+		selector := executor.SelectAll().Filter(where, keys[start:end]...)
+		selector.nullAsZero = this.nullAsZero
+		if err := selector.All(children.Interface()); err != nil {
+			return err
+		}
+
+		childrenVal := children.Elem()
+		for i := 0; i < childrenVal.Len(); i++ {
+			child := childrenVal.Index(i)
+			v, k, err := relationKey(child.Field(cc.Index))
+			if err != nil {
+				return err
+			}
+			if v != nil {
+				groups[k] = append(groups[k], child)
+			}
+		}
+	}
+
+	// assign related rows to parent rows
+	for _, row := range rows {
+		var children []reflect.Value
+		if v, k, _ := relationKey(row.Field(pc.Index)); v != nil {
+			children = groups[k]
+		}
+		field := row.Field(rel.Index)
+		if rel.Kind == RELATION_HAS_MANY {
+			s := reflect.MakeSlice(fieldType, 0, len(children))
+			for _, c := range children {
+				if isPtr {
+					p := reflect.New(childType)
+					p.Elem().Set(c)
+					c = p
+				}
+				s = reflect.Append(s, c)
+			}
+			field.Set(s)
+		} else if len(children) > 0 {
+			if isPtr {
+				p := reflect.New(childType)
+				p.Elem().Set(children[0])
+				field.Set(p)
+			} else {
+				field.Set(children[0])
+			}
+		} else {
+			field.Set(reflect.Zero(fieldType))
+		}
+	}
+	return nil
+}
+
+// relationKey returns the SQL value of a key column and the text to match
+// it, pointers and driver.Valuer are resolved so a *int64 or sql.NullInt64
+// key matches an int64 key. The value is nil for NULL keys which match no
+// rows
+func relationKey(field reflect.Value) (interface{}, string, error) {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil, "", nil
+		}
+		field = field.Elem()
+	}
+
+	v := field.Interface()
+	if valuer, ok := v.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil {
+			return nil, "", err
+		}
+		v = dv
+	}
+	switch b := v.(type) {
+	case nil:
+		return nil, "", nil
+	case []byte:
+		return v, string(b), nil
+	}
+	return v, fmt.Sprint(v), nil
+}
@@ -0,0 +1,125 @@
+package dbx
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreload(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_TABLE)
+	tDatabase.DropTable(USER_LOGIN_TABLE)
+	tDatabase.DropTable(USER_OAUTH_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+	assert.Nil(tDatabase.CreateTable(USER_LOGIN_TABLE))
+	assert.Nil(tDatabase.CreateTable(USER_OAUTH_TABLE))
+
+	var err error
+	for i := range TestUsers {
+		_, err = tDatabase.T(USER_TABLE).Insert(&TestUsers[i])
+		assert.Nil(err)
+	}
+	_, err = tDatabase.T(USER_LOGIN_TABLE).Insert(&TestUserLogins[0])
+	assert.Nil(err)
+	_, err = tDatabase.T(USER_LOGIN_TABLE).Insert(&TestUserLogins[1])
+	assert.Nil(err)
+	_, err = tDatabase.T(USER_OAUTH_TABLE).Insert(&TestUserOAuths[0])
+	assert.Nil(err)
+
+	// has many and has one
+	users := []User{}
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().Asc("id").
+		Preload("Logins", "OAuth").All(&users))
+	assert.Equal(3, len(users))
+	assert.Equal(1, len(users[0].Logins))
+	assert.Equal(TestUserLogins[0].OAuthId, users[0].Logins[0].OAuthId)
+	assert.Equal(1, len(users[1].Logins))
+	assert.Equal(0, len(users[2].Logins))
+	assert.NotNil(users[0].OAuth)
+	assert.Equal(TestUserOAuths[0].Token, users[0].OAuth.Token)
+	assert.Nil(users[1].OAuth)
+
+	// belongs to
+	login := UserLogin{}
+	assert.Nil(tDatabase.T(USER_LOGIN_TABLE).SelectAll().
+		Filter("userid=?", TestUserLogins[1].Userid).Preload("User").One(&login))
+	assert.NotNil(login.User)
+	assert.Equal(TestUsers[1].Nickname, login.User.Nickname)
+
+	// registered relation
+	assert.Nil(tDatabase.RegisterRelation(USER_OAUTH_TABLE, Relation{
+		Name: "Owner", Kind: RELATION_BELONGS_TO, Table: USER_TABLE,
+		ForeignKey: "userid", References: "userid",
+	}))
+	oauths := []*UserOAuth{}
+	assert.Nil(tDatabase.T(USER_OAUTH_TABLE).SelectAll().Preload("Owner").
+		All(&oauths))
+	assert.Equal(1, len(oauths))
+	assert.Equal(TestUsers[0].Nickname, oauths[0].Owner.Nickname)
+
+	// unknown relation
+	assert.NotNil(tDatabase.T(USER_TABLE).SelectAll().Preload("Unknown").
+		All(&users))
+	assert.NotNil(tDatabase.RegisterRelation(USER_OAUTH_TABLE, Relation{
+		Name: "Unknown", Kind: RELATION_BELONGS_TO, Table: USER_TABLE,
+		ForeignKey: "userid",
+	}))
+}
+
+type Team struct {
+	Id   int64  `db:"id" sqlite:"INTEGER PRIMARY KEY AUTOINCREMENT"`
+	Name string `db:"name" sqlite:"TEXT"`
+
+	Members []Member `dbx:"hasMany:member,foreignKey:team_id"`
+}
+
+type Member struct {
+	Id     int64         `db:"id" sqlite:"INTEGER PRIMARY KEY AUTOINCREMENT"`
+	TeamId *int64        `db:"team_id" sqlite:"INTEGER"`
+	LeadOf sql.NullInt64 `db:"lead_of" sqlite:"INTEGER"`
+
+	Team *Team `dbx:"belongsTo:team,foreignKey:team_id"`
+	Lead *Team `dbx:"belongsTo:team,foreignKey:lead_of"`
+}
+
+func TestPreloadNullableKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	db := NewDatabase()
+	assert.Nil(db.OpenSQLite(":memory:"))
+	defer db.Close()
+	db.DB().SetMaxOpenConns(1)
+	assert.Nil(db.RegisterTable("team", Team{}))
+	assert.Nil(db.RegisterTable("member", Member{}))
+	assert.Nil(db.CreateTables())
+
+	rs, err := db.T("team").Insert(&Team{Name: "dbx"})
+	assert.Nil(err)
+	teamId, _ := rs.LastInsertId()
+	_, err = db.T("member").Insert(&Member{TeamId: &teamId,
+		LeadOf: sql.NullInt64{Int64: teamId, Valid: true}})
+	assert.Nil(err)
+	_, err = db.T("member").Insert(&Member{})
+	assert.Nil(err)
+
+	// belongs to by *int64 and sql.NullInt64, NULL keys match nothing
+	members := []Member{}
+	assert.Nil(db.T("member").SelectAll().Asc("id").Preload("Team", "Lead").
+		All(&members))
+	assert.Equal(2, len(members))
+	assert.NotNil(members[0].Team)
+	assert.Equal("dbx", members[0].Team.Name)
+	assert.NotNil(members[0].Lead)
+	assert.Nil(members[1].Team)
+	assert.Nil(members[1].Lead)
+
+	// has many by *int64 foreign key
+	teams := []Team{}
+	assert.Nil(db.T("team").SelectAll().Preload("Members").All(&teams))
+	assert.Equal(1, len(teams))
+	assert.Equal(1, len(teams[0].Members))
+	assert.Equal(members[0].Id, teams[0].Members[0].Id)
+}
@@ -0,0 +1,204 @@
+package dbx
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// fieldScanner scans one column into a struct field. It records whether the
+// column was NULL so the caller can tell an absent joined row apart, and
+// optionally turns NULL into the zero value of the field
+type fieldScanner struct {
+	field  reflect.Value
+	zero   bool
+	isNull bool
+	err    error
+}
+
+func (this *fieldScanner) Scan(src interface{}) error {
+	this.isNull = src == nil
+	this.err = nil
+	if src == nil && this.zero {
+		this.field.Set(reflect.Zero(this.field.Type()))
+		return nil
+	}
+
+	if reflect.PtrTo(this.field.Type()).Implements(scannerType) {
+		return this.field.Addr().Interface().(sql.Scanner).Scan(src)
+	}
+
+	if src == nil {
+		switch this.field.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			this.field.Set(reflect.Zero(this.field.Type()))
+		default:
+			// defer the error, the whole row may turn out to be absent
+			this.err = fmt.Errorf("converting NULL to %s is unsupported",
+				this.field.Type())
+		}
+		return nil
+	}
+	return assignValue(this.field, src)
+}
+
+// rowScanner scans the selected columns of one struct
+type rowScanner struct {
+	fields []fieldScanner
+}
+
+func newRowScanner(row reflect.Value, indexes []int, zero bool) *rowScanner {
+	fields := make([]fieldScanner, len(indexes), len(indexes))
+	for i, j := range indexes {
+		fields[i] = fieldScanner{field: row.Field(j), zero: zero}
+	}
+	return &rowScanner{fields: fields}
+}
+
+// refs fills the scan destinations into refs and returns the number of them
+func (this *rowScanner) refs(refs []interface{}) int {
+	for i := range this.fields {
+		refs[i] = &this.fields[i]
+	}
+	return len(this.fields)
+}
+
+// absent reports whether all scanned columns were NULL
+func (this *rowScanner) absent() bool {
+	if len(this.fields) == 0 {
+		return false
+	}
+	for _, f := range this.fields {
+		if !f.isNull {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *rowScanner) err() error {
+	for _, f := range this.fields {
+		if f.err != nil {
+			return f.err
+		}
+	}
+	return nil
+}
+
+// assignValue converts a non-NULL driver value to the type of dest
+func assignValue(dest reflect.Value, src interface{}) error {
+	if reflect.PtrTo(dest.Type()).Implements(scannerType) {
+		return dest.Addr().Interface().(sql.Scanner).Scan(src)
+	}
+
+	if dest.Kind() == reflect.Ptr {
+		v := reflect.New(dest.Type().Elem())
+		if err := assignValue(v.Elem(), src); err != nil {
+			return err
+		}
+		dest.Set(v)
+		return nil
+	}
+
+	switch s := src.(type) {
+	case []byte:
+		if dest.Kind() == reflect.Slice && dest.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, len(s))
+			copy(b, s)
+			dest.SetBytes(b)
+			return nil
+		}
+		return assignString(dest, string(s))
+	case string:
+		if dest.Kind() == reflect.Slice && dest.Type().Elem().Kind() == reflect.Uint8 {
+			dest.SetBytes([]byte(s))
+			return nil
+		}
+		return assignString(dest, s)
+	case time.Time:
+		if dest.Kind() == reflect.String {
+			dest.SetString(s.Format(time.RFC3339Nano))
+			return nil
+		}
+	case int64:
+		switch dest.Kind() {
+		case reflect.String:
+			dest.SetString(strconv.FormatInt(s, 10))
+			return nil
+		case reflect.Bool:
+			dest.SetBool(s != 0)
+			return nil
+		}
+	case float64:
+		if dest.Kind() == reflect.String {
+			dest.SetString(strconv.FormatFloat(s, 'g', -1, 64))
+			return nil
+		}
+	case bool:
+		switch dest.Kind() {
+		case reflect.String:
+			dest.SetString(strconv.FormatBool(s))
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if s {
+				dest.SetInt(1)
+			} else {
+				dest.SetInt(0)
+			}
+			return nil
+		}
+	}
+
+	sv := reflect.ValueOf(src)
+	if dest.Kind() == reflect.Interface {
+		dest.Set(sv)
+		return nil
+	}
+	if sv.Type().ConvertibleTo(dest.Type()) && dest.Kind() != reflect.String {
+		dest.Set(sv.Convert(dest.Type()))
+		return nil
+	}
+	return fmt.Errorf("unsupported scan, storing %T into %s", src, dest.Type())
+}
+
+// assignString parses a textual driver value into dest
+func assignString(dest reflect.Value, s string) error {
+	switch dest.Kind() {
+	case reflect.String:
+		dest.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("converting %q to bool: %v", s, err)
+		}
+		dest.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, dest.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("converting %q to %s: %v", s, dest.Type(), err)
+		}
+		dest.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, dest.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("converting %q to %s: %v", s, dest.Type(), err)
+		}
+		dest.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, dest.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("converting %q to %s: %v", s, dest.Type(), err)
+		}
+		dest.SetFloat(f)
+	case reflect.Interface:
+		dest.Set(reflect.ValueOf(s))
+	default:
+		return fmt.Errorf("unsupported scan, storing string into %s", dest.Type())
+	}
+	return nil
+}
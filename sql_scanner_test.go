@@ -0,0 +1,95 @@
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanNullableColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_PROFILE_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_PROFILE_TABLE))
+
+	// pointer and sql.Null* fields
+	email := "eschao@test.com"
+	_, err := tDatabase.T(USER_PROFILE_TABLE).Insert(&UserProfile{
+		Userid: TestUsers[0].Userid, Email: &email,
+	})
+	assert.Nil(err)
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Insert(&UserProfile{
+		Userid: TestUsers[1].Userid,
+	})
+	assert.Nil(err)
+
+	profiles := []*UserProfile{}
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().Asc("id").
+		All(&profiles))
+	assert.Equal(2, len(profiles))
+	assert.Equal(email, *profiles[0].Email)
+	assert.False(profiles[0].Age.Valid)
+	assert.Nil(profiles[1].Email)
+
+	// NULL into a string field
+	_, err = tDatabase.DB().Exec("UPDATE user_profile SET bio=NULL")
+	assert.Nil(err)
+	profile := UserProfile{Bio: "bio"}
+	assert.NotNil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().
+		Filter("userid=?", TestUsers[0].Userid).One(&profile))
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().NullAsZero().
+		Filter("userid=?", TestUsers[0].Userid).One(&profile))
+	assert.Equal("", profile.Bio)
+	assert.Equal(email, *profile.Email)
+}
+
+func TestScanLeftJoinAbsentRow(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_TABLE)
+	tDatabase.DropTable(USER_OAUTH_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+	assert.Nil(tDatabase.CreateTable(USER_OAUTH_TABLE))
+
+	var err error
+	_, err = tDatabase.T(USER_TABLE).Insert(&TestUsers[0])
+	assert.Nil(err)
+	_, err = tDatabase.T(USER_TABLE).Insert(&TestUsers[1])
+	assert.Nil(err)
+	_, err = tDatabase.T(USER_OAUTH_TABLE).Insert(&TestUserOAuths[0])
+	assert.Nil(err)
+
+	// absent joined row into struct pointer
+	user := User{}
+	oauth := &UserOAuth{}
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().
+		LeftJoin(USER_OAUTH_TABLE, "userid", "userid").SelectAll().
+		Filter("user.userid=?", TestUsers[1].Userid).One(&user, &oauth))
+	assert.Equal(TestUsers[1].Userid, user.Userid)
+	assert.Nil(oauth)
+
+	// absent joined row into struct value
+	oauthVal := UserOAuth{App: "app"}
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().
+		LeftJoin(USER_OAUTH_TABLE, "userid", "userid").SelectAll().
+		Filter("user.userid=?", TestUsers[1].Userid).One(&user, &oauthVal))
+	assert.Equal(UserOAuth{}, oauthVal)
+
+	// present joined row
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().
+		LeftJoin(USER_OAUTH_TABLE, "userid", "userid").SelectAll().
+		Filter("user.userid=?", TestUsers[0].Userid).One(&user, &oauth))
+	assert.NotNil(oauth)
+	assert.Equal(TestUserOAuths[0].Token, oauth.Token)
+
+	// all rows with slice of pointers
+	users := []User{}
+	oauths := []*UserOAuth{}
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().
+		LeftJoin(USER_OAUTH_TABLE, "userid", "userid").SelectAll().
+		Asc("id").All(&users, &oauths))
+	assert.Equal(2, len(users))
+	assert.Equal(2, len(oauths))
+	assert.NotNil(oauths[0])
+	assert.Nil(oauths[1])
+}
@@ -8,36 +8,55 @@ import (
 	"strings"
 )
 
+// sqlSort sorts columns by op set by Asc or Desc, or by their own orders
+// set by OrderBy when op is empty
 type sqlSort struct {
 	op      string
 	columns []string
+	desc    []bool
 }
 
+// buildSQL returns the ORDER BY clause of sort, columns are qualified by
+// table if it's not empty
 func (this sqlSort) buildSQL(table string) string {
-	s := ""
-	for _, c := range this.columns {
-		s += table + "." + c + ","
+	if len(this.columns) == 0 {
+		return ""
 	}
 
-	if s != "" {
-		return s[:len(s)-1]
-	} else {
-		return ""
+	terms := make([]string, len(this.columns))
+	for i, c := range this.columns {
+		if table != "" {
+			c = table + "." + c
+		}
+		if this.op == "" {
+			if this.desc[i] {
+				c += " DESC"
+			} else {
+				c += " ASC"
+			}
+		}
+		terms[i] = c
 	}
+	s := " ORDER BY " + strings.Join(terms, ",")
+	if this.op != "" {
+		s += " " + this.op
+	}
+	return s
 }
 
 // SQLSelector
 type SQLSelector struct {
-	table       *Table
-	db          *sql.DB
-	tx          *sql.Tx
-	err         error
-	columns     []string
-	filter      sqlFilter
-	limit       int
-	offset      int
-	sort        sqlSort
-	tableGetter tableGetter
+	sqlSession
+	table      *Table
+	err        error
+	columns    []string
+	filter     sqlFilter
+	limit      int
+	offset     int
+	sort       sqlSort
+	nullAsZero bool
+	preloads   []string
+	scope      int
 }
 
 func (this *SQLSelector) buildColumnsSQL() string {
@@ -90,6 +109,26 @@ func (this *SQLSelector) Desc(cols ...string) *SQLSelector {
 	return this
 }
 
+// OrderBy sorts given columns by their own orders, a column prefixed with
+// "-" is sorted by desc, e.g. OrderBy("-update_time", "id")
+func (this *SQLSelector) OrderBy(cols ...string) *SQLSelector {
+	this.sort = sqlSort{
+		columns: make([]string, len(cols)), desc: make([]bool, len(cols)),
+	}
+	for i, c := range cols {
+		this.sort.columns[i] = strings.TrimPrefix(c, "-")
+		this.sort.desc[i] = strings.HasPrefix(c, "-")
+	}
+	return this
+}
+
+// NullAsZero scans NULL columns as the zero value of struct fields instead
+// of failing
+func (this *SQLSelector) NullAsZero() *SQLSelector {
+	this.nullAsZero = true
+	return this
+}
+
 // Limit sets limit of query
 func (this *SQLSelector) Limit(n int) *SQLSelector {
 	this.limit = n
@@ -167,23 +206,27 @@ func (this *SQLSelector) InnerJoin(table, onLeft, onRight string) jointer {
 
 func (this *SQLSelector) buildSQL() string {
 	q := "SELECT " + strings.Join(this.columns, ",") + " FROM " + this.table.Name
-	if this.filter.where != "" {
-		q += " WHERE " + this.filter.where
-	}
-	if len(this.sort.columns) > 0 {
-		q += " ORDER BY " + strings.Join(this.sort.columns, ",") + " " + this.sort.op
+	where := scopeWhere(this.filter.where, this.table.scopeSQL(this.scope))
+	if where != "" {
+		q += " WHERE " + where
 	}
+	q += this.sort.buildSQL("")
 	if this.limit > 0 {
 		q += " LIMIT " + strconv.Itoa(this.limit)
 	}
 	if this.offset > 0 {
 		q += " OFFSET " + strconv.Itoa(this.offset)
 	}
+	return q
+}
 
-	if dbLogger != nil {
-		dbLogger(q)
+// ToSQL returns the query and its arguments without executing it, e.g. to
+// use it as a subquery
+func (this *SQLSelector) ToSQL() (string, []interface{}, error) {
+	if this.err != nil {
+		return "", nil, this.err
 	}
-	return q
+	return this.buildSQL(), this.filter.args, nil
 }
 
 func (this *SQLSelector) Run() (*sql.Rows, error) {
@@ -192,11 +235,7 @@ func (this *SQLSelector) Run() (*sql.Rows, error) {
 	}
 
 	q := this.buildSQL()
-	if this.tx != nil {
-		return this.tx.Query(q, this.filter.args...)
-	} else {
-		return this.db.Query(q, this.filter.args...)
-	}
+	return this.rows(this.table.Name, OP_SELECT, q, this.filter.args)
 }
 
 // One selects one row from table
@@ -205,22 +244,34 @@ func (this *SQLSelector) One(row interface{}) error {
 		return this.err
 	}
 
+	rowVal, err := this.table.rowValue(row)
+	if err != nil {
+		return err
+	}
 	size := len(this.columns)
 	refs := make([]interface{}, size, size)
-	rowVal := reflect.ValueOf(row).Elem()
-	for i, n := range this.columns {
-		col := this.table.Columns[n]
-		refs[i] = rowVal.Field(col.Index).Addr().Interface()
+	indexes := this.columnIndexes()
+	if this.nullAsZero {
+		newRowScanner(rowVal, indexes, true).refs(refs)
+	} else {
+		for i, j := range indexes {
+			refs[i] = rowVal.Field(j).Addr().Interface()
+		}
 	}
 
 	q := this.buildSQL()
-	var rs *sql.Row
-	if this.tx != nil {
-		rs = this.tx.QueryRow(q, this.filter.args...)
-	} else {
-		rs = this.db.QueryRow(q, this.filter.args...)
+	err = this.queryRow(this.table.Name, OP_SELECT, q, this.filter.args,
+		refs...)
+	if err != nil {
+		return err
 	}
-	return rs.Scan(refs...)
+
+	if len(this.preloads) > 0 {
+		if err := this.preload([]reflect.Value{rowVal}); err != nil {
+			return err
+		}
+	}
+	return afterFind(this.executor(), rowVal)
 }
 
 // One selects one row from table
@@ -238,13 +289,8 @@ func (this *SQLSelector) Values(values ...interface{}) error {
 	}
 
 	q := this.buildSQL()
-	var rs *sql.Row
-	if this.tx != nil {
-		rs = this.tx.QueryRow(q, this.filter.args...)
-	} else {
-		rs = this.db.QueryRow(q, this.filter.args...)
-	}
-	return rs.Scan(values...)
+	return this.queryRow(this.table.Name, OP_SELECT, q, this.filter.args,
+		values...)
 }
 
 // All selects all rows from table
@@ -259,55 +305,92 @@ func (this *SQLSelector) All(rows interface{}) error {
 	}
 
 	size := len(this.columns)
-	indexes := make([]int, size, size)
-	for i, n := range this.columns {
-		col := this.table.Columns[n]
-		indexes[i] = col.Index
-	}
-
-	q := this.buildSQL()
-	var rs *sql.Rows
-	var err error
-	if this.tx != nil {
-		rs, err = this.tx.Query(q, this.filter.args...)
-	} else {
-		rs, err = this.db.Query(q, this.filter.args...)
-	}
-	if err != nil {
-		return err
-	}
-	defer rs.Close()
+	indexes := this.columnIndexes()
 
 	sliceVal := rowsVal.Elem()
 	sliceVal = sliceVal.Slice(0, sliceVal.Cap())
 	rowType := sliceVal.Type().Elem()
+	isPtr := rowType.Kind() == reflect.Ptr
+	if isPtr {
+		rowType = rowType.Elem()
+	}
+	if rowType.Kind() != reflect.Struct ||
+		!rowType.ConvertibleTo(this.table.rowType) {
+		return fmt.Errorf("rows argument must be a slice of %s table rows, "+
+			"not %T", this.table.Name, rows)
+	}
 	refs := make([]interface{}, size, size)
 	i := 0
 
-	for rs.Next() {
-		if sliceVal.Len() == i {
-			row := reflect.New(rowType).Elem()
-			//fmt.Printf("New Row: %v\n", row)
-			for k, j := range indexes {
-				refs[k] = row.Field(j).Addr().Interface()
-			}
-			if err := rs.Scan(refs...); err != nil {
-				return err
-			}
-			sliceVal = reflect.Append(sliceVal, row)
-		} else {
-			row := sliceVal.Index(i)
-			//fmt.Printf("Row At: %v\n", row)
-			for k, j := range indexes {
-				refs[k] = row.Field(j).Addr().Interface()
-			}
-			if err := rs.Scan(refs...); err != nil {
-				return err
+	q := this.buildSQL()
+	err := this.query(this.table.Name, OP_SELECT, q, this.filter.args,
+		func(rs *sql.Rows) (int64, error) {
+			for rs.Next() {
+				if sliceVal.Len() == i {
+					p := reflect.New(rowType)
+					if isPtr {
+						sliceVal = reflect.Append(sliceVal, p)
+					} else {
+						sliceVal = reflect.Append(sliceVal, p.Elem())
+					}
+				} else if isPtr && sliceVal.Index(i).IsNil() {
+					sliceVal.Index(i).Set(reflect.New(rowType))
+				}
+
+				row := reflect.Indirect(sliceVal.Index(i))
+				if this.nullAsZero {
+					newRowScanner(row, indexes, true).refs(refs)
+				} else {
+					for k, j := range indexes {
+						refs[k] = row.Field(j).Addr().Interface()
+					}
+				}
+				if err := rs.Scan(refs...); err != nil {
+					return int64(i), err
+				}
+				i++
 			}
+			return int64(i), nil
+		})
+	if err != nil {
+		return err
+	}
+	rowsVal.Elem().Set(sliceVal) //.Slice(0, i))
+
+	scanned := make([]reflect.Value, i, i)
+	for k := 0; k < i; k++ {
+		scanned[k] = reflect.Indirect(sliceVal.Index(k))
+	}
+	if len(this.preloads) > 0 && i > 0 {
+		if err := this.preload(scanned); err != nil {
+			return err
 		}
-		i++
 	}
+	return afterFind(this.executor(), scanned...)
+}
 
-	rowsVal.Elem().Set(sliceVal) //.Slice(0, i))
-	return nil
+// Count counts rows by the filter of selector, its columns, sort, limit and
+// offset are ignored, e.g. to count rows of all pages
+func (this *SQLSelector) Count() (int, error) {
+	if this.err != nil {
+		return 0, this.err
+	}
+	return this.executor().Count(this.filter.where, this.filter.args...)
+}
+
+func (this *SQLSelector) executor() *SQLExecutor {
+	return &SQLExecutor{
+		sqlSession: this.sqlSession, table: this.table, scope: this.scope,
+	}
+}
+
+// columnIndexes returns field indexes of the selected columns
+func (this *SQLSelector) columnIndexes() []int {
+	size := len(this.columns)
+	indexes := make([]int, size, size)
+	for i, n := range this.columns {
+		col := this.table.Columns[n]
+		indexes[i] = col.Index
+	}
+	return indexes
 }
@@ -65,3 +65,34 @@ func TestSelect(t *testing.T) {
 	assert.Equal(users1[0].Userid, TestUsers[1].Userid)
 	assert.Equal(users1[1].Userid, TestUsers[0].Userid)
 }
+
+func TestSelectToSQL(t *testing.T) {
+	assert := assert.New(t)
+
+	q, args, err := tDatabase.T(USER_TABLE).Select("id", "userid").
+		Filter("nickname=?", "eschao").Desc("id").Limit(10).Offset(20).ToSQL()
+	assert.Nil(err)
+	assert.Equal("SELECT id,userid FROM user WHERE nickname=? ORDER BY id DESC"+
+		" LIMIT 10 OFFSET 20", q)
+	assert.Equal([]interface{}{"eschao"}, args)
+
+	// as a subquery
+	sub, subArgs, err := tDatabase.T(USER_LOGIN_TABLE).Select("userid").
+		Filter("last_ip=?", 1).ToSQL()
+	assert.Nil(err)
+	q, args, err = tDatabase.T(USER_TABLE).Select("id").
+		Filter("userid IN ("+sub+")", subArgs...).ToSQL()
+	assert.Nil(err)
+	assert.Equal("SELECT id FROM user WHERE userid IN "+
+		"(SELECT userid FROM user_login WHERE last_ip=?)", q)
+	assert.Equal([]interface{}{1}, args)
+
+	// mixed orders
+	q, _, err = tDatabase.T(USER_TABLE).Select("id").
+		OrderBy("-update_time", "id").ToSQL()
+	assert.Nil(err)
+	assert.Equal("SELECT id FROM user ORDER BY update_time DESC,id ASC", q)
+
+	_, _, err = tDatabase.T("no_such_table").Select("id").ToSQL()
+	assert.NotNil(err)
+}
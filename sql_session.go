@@ -0,0 +1,169 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// sqlConn is implemented by both *sql.DB and *sql.Tx
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (
+		sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (
+		*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// sqlSession is shared by executor, selector and updater to run statements
+// in a database or transaction
+type sqlSession struct {
+	db          *sql.DB
+	tx          *sql.Tx
+	database    *Database
+	tableGetter tableGetter
+	ctx         context.Context
+	logger      Logger
+}
+
+func (this *sqlSession) conn() sqlConn {
+	if this.tx != nil {
+		return this.tx
+	}
+	return this.db
+}
+
+func (this *sqlSession) context() context.Context {
+	if this.ctx == nil {
+		return context.Background()
+	}
+	return this.ctx
+}
+
+func (this *sqlSession) call(table, op, q string, args []interface{}) *Call {
+	return &Call{Ctx: this.context(), Op: op, Table: table, SQL: q, Args: args}
+}
+
+// log sends the event to logger of session, or logger of database if session
+// doesn't have one. The package logger set by SetLogger is used if neither
+// of them is set. Slow queries are reported as well
+func (this *sqlSession) log(ev *QueryEvent) {
+	logger := this.logger
+	if logger == nil && this.database != nil {
+		logger = this.database.logger
+	}
+
+	if logger != nil {
+		logger.Log(ev)
+	} else if dbLogger != nil {
+		dbLogger(ev.SQL)
+	}
+	this.checkSlow(ev)
+}
+
+// exec executes a statement of table
+func (this *sqlSession) exec(table, op, q string, args ...interface{}) (
+	sql.Result, error) {
+	if this.dryRunning(table, op, q, args) {
+		return dryRunResult{}, nil
+	}
+
+	start := time.Now()
+	var rs sql.Result
+	c := this.call(table, op, q, args)
+	err := this.database.intercept(c, func() error {
+		var err error
+		rs, err = this.conn().ExecContext(c.Ctx, q, args...)
+		return err
+	})
+
+	ev := &QueryEvent{
+		SQL: q, Args: args, Duration: time.Since(start), RowsAffected: -1,
+		Err: err, Table: table, Op: op,
+	}
+	if err == nil && rs != nil {
+		if n, err := rs.RowsAffected(); err == nil {
+			ev.RowsAffected = n
+		}
+	}
+	this.log(ev)
+	return rs, err
+}
+
+// query executes a query of table and reads rows by scan which returns the
+// number of scanned rows, the rows are closed after scan
+func (this *sqlSession) query(table, op, q string, args []interface{},
+	scan func(rs *sql.Rows) (int64, error)) error {
+	if this.dryRunning(table, op, q, args) {
+		return nil
+	}
+
+	start := time.Now()
+	n := int64(-1)
+	c := this.call(table, op, q, args)
+	err := this.database.intercept(c, func() error {
+		rs, err := this.conn().QueryContext(c.Ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		defer rs.Close()
+
+		if n, err = scan(rs); err != nil {
+			return err
+		}
+		return rs.Err()
+	})
+
+	this.log(&QueryEvent{
+		SQL: q, Args: args, Duration: time.Since(start), RowsAffected: n,
+		Err: err, Table: table, Op: op,
+	})
+	return err
+}
+
+// rows executes a query of table and returns rows to caller
+func (this *sqlSession) rows(table, op, q string, args []interface{}) (
+	*sql.Rows, error) {
+	if this.dryRunning(table, op, q, args) {
+		return nil, ErrDryRun
+	}
+
+	start := time.Now()
+	var rs *sql.Rows
+	c := this.call(table, op, q, args)
+	err := this.database.intercept(c, func() error {
+		var err error
+		rs, err = this.conn().QueryContext(c.Ctx, q, args...)
+		return err
+	})
+
+	this.log(&QueryEvent{
+		SQL: q, Args: args, Duration: time.Since(start), RowsAffected: -1,
+		Err: err, Table: table, Op: op,
+	})
+	return rs, err
+}
+
+// queryRow executes a query of table and scans one row into dest
+func (this *sqlSession) queryRow(table, op, q string, args []interface{},
+	dest ...interface{}) error {
+	if this.dryRunning(table, op, q, args) {
+		return sql.ErrNoRows
+	}
+
+	start := time.Now()
+	c := this.call(table, op, q, args)
+	err := this.database.intercept(c, func() error {
+		return this.conn().QueryRowContext(c.Ctx, q, args...).Scan(dest...)
+	})
+	n := int64(1)
+	if err != nil {
+		n = 0
+	}
+
+	this.log(&QueryEvent{
+		SQL: q, Args: args, Duration: time.Since(start), RowsAffected: n,
+		Err: err, Table: table, Op: op,
+	})
+	return err
+}
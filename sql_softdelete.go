@@ -0,0 +1,100 @@
+package dbx
+
+import (
+	"fmt"
+)
+
+// scopes of soft deleted rows
+const (
+	scopeDefault = iota
+	scopeUnscoped
+	scopeOnlyDeleted
+)
+
+// SoftDeleteColumn returns the name of soft delete column, empty if table
+// doesn't support soft delete
+func (this *Table) SoftDeleteColumn() string {
+	for _, c := range this.orderedColumns() {
+		if c.IsSoftDelete {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// scopeSQL returns the condition of soft deleted rows for the given scope
+func (this *Table) scopeSQL(scope int) string {
+	col := this.SoftDeleteColumn()
+	if col == "" || scope == scopeUnscoped {
+		return ""
+	}
+	if scope == scopeOnlyDeleted {
+		return this.Name + "." + col + " IS NOT NULL"
+	}
+	return this.Name + "." + col + " IS NULL"
+}
+
+// scopeWhere appends the condition of soft deleted rows to where
+func scopeWhere(where, cond string) string {
+	if cond == "" {
+		return where
+	}
+	if where == "" {
+		return cond
+	}
+	return "(" + where + ") AND " + cond
+}
+
+// Unscoped returns an executor which doesn't exclude soft deleted rows, and
+// whose Delete deletes rows physically
+func (this *SQLExecutor) Unscoped() *SQLExecutor {
+	e := *this
+	e.scope = scopeUnscoped
+	return &e
+}
+
+// OnlyDeleted returns an executor which only queries soft deleted rows
+func (this *SQLExecutor) OnlyDeleted() *SQLExecutor {
+	e := *this
+	e.scope = scopeOnlyDeleted
+	return &e
+}
+
+// softDeleteSQL returns the statement marking rows by given filter as deleted
+func (this *SQLExecutor) softDeleteSQL(col, where string,
+	args ...interface{}) (string, []interface{}, error) {
+	c := this.table.Columns[col]
+	now, err := timestampValue(c, this.table.rowType.Field(c.Index).Type,
+		this.driver(), this.database.now())
+	if err != nil {
+		return "", nil, err
+	}
+
+	q := "UPDATE " + this.table.Name + " SET " + col + "=?"
+	where = scopeWhere(where, this.table.scopeSQL(scopeDefault))
+	q += " WHERE " + where
+	return q, append([]interface{}{now}, args...), nil
+}
+
+// Restore restores soft deleted rows by given filter
+func (this *SQLExecutor) Restore(where string, args ...interface{}) error {
+	if this.err != nil {
+		return this.err
+	}
+
+	col := this.table.SoftDeleteColumn()
+	if col == "" {
+		return fmt.Errorf("%s table doesn't support soft delete", this.table.Name)
+	}
+
+	q := "UPDATE " + this.table.Name + " SET " + col + "=NULL WHERE " +
+		scopeWhere(where, this.table.scopeSQL(scopeOnlyDeleted))
+	_, err := this.exec(this.table.Name, OP_UPDATE, q, args...)
+	return err
+}
+
+// ForceDelete deletes rows by given filter physically even if the table
+// supports soft delete
+func (this *SQLExecutor) ForceDelete(where string, args ...interface{}) error {
+	return this.Unscoped().Delete(where, args...)
+}
@@ -0,0 +1,73 @@
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoftDelete(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_TABLE)
+	tDatabase.DropTable(USER_PROFILE_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+	assert.Nil(tDatabase.CreateTable(USER_PROFILE_TABLE))
+
+	var err error
+	for _, u := range TestUsers {
+		_, err = tDatabase.T(USER_TABLE).Insert(&u)
+		assert.Nil(err)
+		_, err = tDatabase.T(USER_PROFILE_TABLE).Insert(&UserProfile{Userid: u.Userid})
+		assert.Nil(err)
+	}
+
+	// soft delete
+	profiles := tDatabase.T(USER_PROFILE_TABLE)
+	assert.Nil(profiles.Delete("userid=?", TestUsers[0].Userid))
+	n, err := profiles.CountAll()
+	assert.Nil(err)
+	assert.Equal(2, n)
+	n, err = profiles.Count("userid=?", TestUsers[0].Userid)
+	assert.Nil(err)
+	assert.Equal(0, n)
+	n, err = profiles.Unscoped().CountAll()
+	assert.Nil(err)
+	assert.Equal(3, n)
+
+	rows := []UserProfile{}
+	assert.Nil(profiles.SelectAll().All(&rows))
+	assert.Equal(2, len(rows))
+	rows = []UserProfile{}
+	assert.Nil(profiles.OnlyDeleted().SelectAll().All(&rows))
+	assert.Equal(1, len(rows))
+	assert.Equal(TestUsers[0].Userid, rows[0].Userid)
+	assert.True(rows[0].DeletedAt.Valid)
+
+	// joins exclude soft deleted rows
+	user := User{}
+	var profile *UserProfile
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().
+		LeftJoin(USER_PROFILE_TABLE, "userid", "userid").SelectAll().
+		Filter("user.userid=?", TestUsers[0].Userid).One(&user, &profile))
+	assert.Nil(profile)
+
+	// restore
+	assert.Nil(profiles.Restore("userid=?", TestUsers[0].Userid))
+	n, err = profiles.CountAll()
+	assert.Nil(err)
+	assert.Equal(3, n)
+
+	// force delete
+	assert.Nil(profiles.ForceDelete("userid=?", TestUsers[0].Userid))
+	n, err = profiles.Unscoped().CountAll()
+	assert.Nil(err)
+	assert.Equal(2, n)
+
+	// table without soft delete column
+	assert.NotNil(tDatabase.T(USER_TABLE).Restore(""))
+	assert.Nil(tDatabase.T(USER_TABLE).Delete("userid=?", TestUsers[1].Userid))
+	n, err = tDatabase.T(USER_TABLE).Unscoped().CountAll()
+	assert.Nil(err)
+	assert.Equal(2, n)
+}
@@ -4,16 +4,23 @@ import (
 	"database/sql"
 	"fmt"
 	"reflect"
+	"sort"
 )
 
 // SQLUpdater
 type SQLUpdater struct {
+	sqlSession
 	table   *Table
-	db      *sql.DB
-	tx      *sql.Tx
 	err     error
 	columns []string
 	filter  sqlFilter
+	// version checked by Values and ValueMap, if hasVersion is set
+	version    interface{}
+	hasVersion bool
+}
+
+func (this *SQLUpdater) executor() *SQLExecutor {
+	return &SQLExecutor{sqlSession: this.sqlSession, table: this.table}
 }
 
 // Set sets columns to be updated
@@ -22,83 +29,180 @@ func (this *SQLUpdater) Set(cols ...string) *SQLUpdater {
 	return this
 }
 
-// Values updates given values to table
+// Version limits Values and ValueMap to rows of version v, ErrStaleObject
+// is returned if no row is updated. Value checks the version of row instead
+func (this *SQLUpdater) Version(v interface{}) *SQLUpdater {
+	if this.err == nil && this.table.VersionColumn() == "" {
+		this.err = fmt.Errorf("%s table has no version column", this.table.Name)
+	}
+	this.version = v
+	this.hasVersion = true
+	return this
+}
+
+// Values updates given values to table, the version column is increased if
+// table has one
 func (this *SQLUpdater) Values(values ...interface{}) (sql.Result, error) {
+	q, args, err := this.ToSQL(values...)
+	if err != nil {
+		return nil, err
+	}
+	return this.execVersion(q, args)
+}
+
+// ToSQL returns the statement of Values and its arguments without executing
+// it
+func (this *SQLUpdater) ToSQL(values ...interface{}) (string, []interface{},
+	error) {
 	if this.err != nil {
-		return nil, this.err
+		return "", nil, this.err
 	}
 
 	// if not given columns, all columns will be updated
 	size := len(this.columns)
 	if size < 1 {
-		return nil, fmt.Errorf("please specify columns to update")
+		return "", nil, fmt.Errorf("please specify columns to update")
 	}
 
 	if len(values) != size {
-		return nil, fmt.Errorf("the specified columns and values are not equal")
+		return "", nil, fmt.Errorf(
+			"the specified columns and values are not equal")
+	}
+
+	names, stamps, err := this.updateTimestamps(this.columns)
+	if err != nil {
+		return "", nil, err
 	}
+	names = append(this.columns[:size:size], names...)
+	values = append(values[:size:size], stamps...)
 
 	cols := ""
-	for _, n := range this.columns {
+	for _, n := range names {
 		if n != "" {
 			cols += n + "=?,"
 		}
 	}
 
 	if cols == "" {
-		return nil, fmt.Errorf("no specified columns to update")
+		return "", nil, fmt.Errorf("no specified columns to update")
 	}
+	cols += this.versionIncrement(names)
 	cols = cols[:len(cols)-1]
 	q := "UPDATE " + this.table.Name + " SET " + cols
-	if this.filter.where != "" {
-		q += " WHERE " + this.filter.where
-		values = append(values, this.filter.args...)
-	}
-	if dbLogger != nil {
-		dbLogger(q)
-	}
-
-	if this.tx != nil {
-		return this.tx.Exec(q, values...)
-	} else {
-		return this.db.Exec(q, values...)
+	if where, args := this.whereVersion(); where != "" {
+		q += " WHERE " + where
+		values = append(values, args...)
 	}
+	return q, values, nil
 }
 
+// ValueMap updates given column values to table, the version column is
+// increased if table has one
 func (this *SQLUpdater) ValueMap(valMap map[string]interface{}) (
 	sql.Result, error) {
+	q, args, err := this.ValueMapSQL(valMap)
+	if err != nil {
+		return nil, err
+	}
+	return this.execVersion(q, args)
+}
+
+// ValueMapSQL returns the statement of ValueMap and its arguments without
+// executing it
+func (this *SQLUpdater) ValueMapSQL(valMap map[string]interface{}) (
+	string, []interface{}, error) {
 	if this.err != nil {
-		return nil, this.err
+		return "", nil, this.err
 	}
 
 	n := len(valMap)
 	if n < 1 {
-		return nil, fmt.Errorf("please specify columns to update")
+		return "", nil, fmt.Errorf("please specify columns to update")
+	}
+
+	// columns are set in the order of table columns, so the statement is the
+	// same for the same columns
+	names := make([]string, 0, n)
+	for _, c := range this.table.orderedColumns() {
+		if _, ok := valMap[c.Name]; ok {
+			names = append(names, c.Name)
+		}
+	}
+	if len(names) < n {
+		others := []string{}
+		for col := range valMap {
+			if _, ok := this.table.Columns[col]; !ok {
+				others = append(others, col)
+			}
+		}
+		sort.Strings(others)
+		names = append(names, others...)
 	}
 
-	i := 0
 	cols := ""
 	vals := make([]interface{}, n)
-	for col, val := range valMap {
+	for i, col := range names {
+		cols += col + "=?,"
+		vals[i] = valMap[col]
+	}
+
+	stampCols, stamps, err := this.updateTimestamps(names)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, col := range stampCols {
 		cols += col + "=?,"
-		vals[i] = val
-		i += 1
 	}
+	vals = append(vals, stamps...)
+	cols += this.versionIncrement(names)
 	cols = cols[:len(cols)-1]
 	q := "UPDATE " + this.table.Name + " SET " + cols
-	if this.filter.where != "" {
-		q += " WHERE " + this.filter.where
-		vals = append(vals, this.filter.args...)
+	if where, args := this.whereVersion(); where != "" {
+		q += " WHERE " + where
+		vals = append(vals, args...)
 	}
-	if dbLogger != nil {
-		dbLogger(q)
+	return q, vals, nil
+}
+
+// versionIncrement returns the SET term increasing version column, so rows
+// updated by Values and ValueMap are detected as stale by Value as well. It's
+// empty if table has no version column or it's set by names explicitly
+func (this *SQLUpdater) versionIncrement(names []string) string {
+	version := this.table.VersionColumn()
+	if version == "" || containsString(names, version) {
+		return ""
 	}
+	return version + "=" + version + "+1,"
+}
 
-	if this.tx != nil {
-		return this.tx.Exec(q, vals...)
-	} else {
-		return this.db.Exec(q, vals...)
+// whereVersion returns the filter of Values and ValueMap with the version
+// given by Version
+func (this *SQLUpdater) whereVersion() (string, []interface{}) {
+	if !this.hasVersion {
+		return this.filter.where, this.filter.args
 	}
+	args := this.filter.args
+	return scopeWhere(this.filter.where, this.table.VersionColumn()+"=?"),
+		append(args[:len(args):len(args)], this.version)
+}
+
+// execVersion executes update q of Values or ValueMap, ErrStaleObject is
+// returned if version is given by Version and no row is updated
+func (this *SQLUpdater) execVersion(q string, args []interface{}) (
+	sql.Result, error) {
+	rs, err := this.exec(this.table.Name, OP_UPDATE, q, args...)
+	if err != nil || !this.hasVersion || this.database.isDryRun() {
+		return rs, err
+	}
+
+	n, err := rs.RowsAffected()
+	if err != nil {
+		return rs, err
+	}
+	if n == 0 {
+		return rs, &ErrStaleObject{Table: this.table.Name, Version: this.version}
+	}
+	return rs, nil
 }
 
 // Value updates given row to table
@@ -107,44 +211,129 @@ func (this *SQLUpdater) Value(row interface{}) (sql.Result, error) {
 		return nil, this.err
 	}
 
+	rowVal, err := this.table.rowValue(row)
+	if err != nil {
+		return nil, err
+	}
+	e := this.executor()
+	if h, ok := row.(BeforeUpdater); ok {
+		if err := h.BeforeUpdate(e); err != nil {
+			return nil, err
+		}
+	}
+
+	q, vals, version, err := this.valueSQL(rowVal)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := this.exec(this.table.Name, OP_UPDATE, q, vals...)
+	if err != nil {
+		return nil, err
+	}
+
+	// no row is updated in dry-run mode, the version isn't checked
+	if version != "" && !this.database.isDryRun() {
+		n, err := rs.RowsAffected()
+		if err != nil {
+			return rs, err
+		}
+		field := rowVal.Field(this.table.Columns[version].Index)
+		if n == 0 {
+			return rs, &ErrStaleObject{Table: this.table.Name,
+				Version: field.Interface()}
+		}
+		if field.Kind() >= reflect.Uint && field.Kind() <= reflect.Uint64 {
+			field.SetUint(field.Uint() + 1)
+		} else {
+			field.SetInt(field.Int() + 1)
+		}
+	}
+
+	if h, ok := row.(AfterUpdater); ok {
+		if err := h.AfterUpdate(e); err != nil {
+			return rs, err
+		}
+	}
+	return rs, nil
+}
+
+// ValueSQL returns the statement of Value and its arguments without executing
+// it, timestamps are set to a copy of row and hooks are not run
+func (this *SQLUpdater) ValueSQL(row interface{}) (string, []interface{},
+	error) {
+	if this.err != nil {
+		return "", nil, this.err
+	}
+	rowVal, err := this.table.rowValue(row)
+	if err != nil {
+		return "", nil, err
+	}
+
+	copied := reflect.New(rowVal.Type()).Elem()
+	copied.Set(rowVal)
+	q, vals, _, err := this.valueSQL(copied)
+	return q, vals, err
+}
+
+// valueSQL returns the statement of Value with the version column, auto
+// update time columns of rowVal are set
+func (this *SQLUpdater) valueSQL(rowVal reflect.Value) (string,
+	[]interface{}, string, error) {
 	// if not given columns, all columns will be updated
-	size := len(this.columns)
-	if size < 1 {
-		this.columns = this.table.ColumnNames()
-		size = len(this.columns)
+	names := this.columns
+	if len(names) < 1 {
+		names = this.table.ColumnNames()
+	}
+
+	// set auto update time columns of row and update them as well
+	names = names[:len(names):len(names)]
+	e := this.executor()
+	now := this.database.now()
+	for _, c := range this.table.orderedColumns() {
+		if c.IsAutoUpdateTime {
+			if _, err := setTimestamp(rowVal, c, e.driver(), now); err != nil {
+				return "", nil, "", err
+			}
+			if !containsString(names, c.Name) {
+				names = append(names, c.Name)
+			}
+		}
 	}
 
 	cols := ""
 	vals := []interface{}{}
-	rowVal := reflect.ValueOf(row).Elem()
-	for _, n := range this.columns {
+	for _, n := range names {
 		col, ok := this.table.Columns[n]
 		if !ok {
-			return nil, fmt.Errorf("column %s is not found", n)
+			return "", nil, "", fmt.Errorf("column %s is not found", n)
 		}
 
-		if !col.IsAutoIncrement {
+		if !col.IsAutoIncrement && !col.IsVersion {
 			cols += n + "=?,"
 			vals = append(vals, rowVal.Field(col.Index).Interface())
 		}
 	}
 
 	if cols == "" {
-		return nil, fmt.Errorf("no specified columns to update")
-	}
-	cols = cols[:len(cols)-1]
-	q := "UPDATE " + this.table.Name + " SET " + cols
-	if this.filter.where != "" {
-		q += " WHERE " + this.filter.where
-		vals = append(vals, this.filter.args...)
+		return "", nil, "", fmt.Errorf("no specified columns to update")
 	}
-	if dbLogger != nil {
-		dbLogger(q)
+
+	// the row is only updated if its version is not changed
+	where := this.filter.where
+	args := this.filter.args
+	version := this.table.VersionColumn()
+	if version != "" {
+		cols += version + "=" + version + "+1,"
+		where = scopeWhere(where, version+"=?")
+		args = append(args[:len(args):len(args)],
+			rowVal.Field(this.table.Columns[version].Index).Interface())
 	}
 
-	if this.tx != nil {
-		return this.tx.Exec(q, vals...)
-	} else {
-		return this.db.Exec(q, vals...)
+	cols = cols[:len(cols)-1]
+	q := "UPDATE " + this.table.Name + " SET " + cols
+	if where != "" {
+		q += " WHERE " + where
+		vals = append(vals, args...)
 	}
+	return q, vals, version, nil
 }
@@ -1,7 +1,9 @@
 package dbx
 
 import (
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -70,3 +72,56 @@ func TestUpdate(t *testing.T) {
 	assert.Equal(user5.Password, "password4")
 	assert.Equal(user5.UpdateTime, "2019-05-01 00:00:00")
 }
+
+func TestUpdateToSQL(t *testing.T) {
+	assert := assert.New(t)
+
+	q, args, err := tDatabase.T(USER_TABLE).Update("userid=?", "u1").
+		Set("nickname", "password").ToSQL("nick", "pass")
+	assert.Nil(err)
+	assert.Equal("UPDATE user SET nickname=?,password=? WHERE userid=?", q)
+	assert.Equal([]interface{}{"nick", "pass", "u1"}, args)
+
+	q, args, err = tDatabase.T(USER_TABLE).Update("userid=?", "u1").
+		ValueMapSQL(map[string]interface{}{"nickname": "nick"})
+	assert.Nil(err)
+	assert.Equal("UPDATE user SET nickname=? WHERE userid=?", q)
+	assert.Equal([]interface{}{"nick", "u1"}, args)
+
+	// columns of map are in the order of table columns
+	valMap := map[string]interface{}{"update_time": "t", "password": "pass",
+		"nickname": "nick", "userid": "u2"}
+	for i := 0; i < 10; i++ {
+		q, args, err = tDatabase.T(USER_TABLE).Update("id=?", 1).
+			ValueMapSQL(valMap)
+		assert.Nil(err)
+		assert.Equal("UPDATE user SET userid=?,nickname=?,password=?,"+
+			"update_time=? WHERE id=?", q)
+		assert.Equal([]interface{}{"u2", "nick", "pass", "t", 1}, args)
+	}
+
+	_, _, err = tDatabase.T(USER_TABLE).Update("").Set("nickname").ToSQL()
+	assert.NotNil(err)
+
+	// row with timestamps and version, row isn't changed
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.Local)
+	tDatabase.SetClock(func() time.Time { return now })
+	defer tDatabase.SetClock(nil)
+	profile := UserProfile{Id: 1, Bio: "bio", Version: 3}
+	q, args, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", 1).
+		Set("bio").ValueSQL(&profile)
+	assert.Nil(err)
+	assert.Equal("UPDATE user_profile SET bio=?,updated_at=?,version=version+1"+
+		" WHERE (id=?) AND version=?", q)
+	assert.Equal([]interface{}{"bio", strconv.FormatInt(now.Unix(), 10), 1,
+		int64(3)}, args)
+	assert.Equal("", profile.UpdatedAt)
+
+	// version given by Version
+	q, args, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", 1).
+		Version(int64(3)).ValueMapSQL(map[string]interface{}{"bio": "bio"})
+	assert.Nil(err)
+	assert.Equal("UPDATE user_profile SET bio=?,updated_at=?,version=version+1"+
+		" WHERE (id=?) AND version=?", q)
+	assert.Equal([]interface{}{"bio", now.Unix(), 1, int64(3)}, args)
+}
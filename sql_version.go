@@ -0,0 +1,30 @@
+package dbx
+
+import (
+	"fmt"
+)
+
+// ErrStaleObject is returned by SQLUpdater.Value if the row to be updated
+// has been changed by others since it was read, i.e. no row matches its
+// version. Values and ValueMap return it as well if the version is given by
+// SQLUpdater.Version and no row is updated
+type ErrStaleObject struct {
+	Table   string
+	Version interface{}
+}
+
+func (this *ErrStaleObject) Error() string {
+	return fmt.Sprintf("stale row of %s table with version %v", this.Table,
+		this.Version)
+}
+
+// VersionColumn returns the name of version column used for optimistic
+// locking, empty if table doesn't have one
+func (this *Table) VersionColumn() string {
+	for _, c := range this.orderedColumns() {
+		if c.IsVersion {
+			return c.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptimisticLocking(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_PROFILE_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_PROFILE_TABLE))
+
+	_, err := tDatabase.T(USER_PROFILE_TABLE).Insert(&UserProfile{
+		Userid: TestUsers[0].Userid,
+	})
+	assert.Nil(err)
+
+	// read the same row twice
+	profile1 := UserProfile{}
+	profile2 := UserProfile{}
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().
+		Filter("userid=?", TestUsers[0].Userid).One(&profile1))
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().
+		Filter("userid=?", TestUsers[0].Userid).One(&profile2))
+	assert.Equal(int64(0), profile1.Version)
+
+	// first update succeeds and increases version
+	profile1.Bio = "bio1"
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", profile1.Id).
+		Value(&profile1)
+	assert.Nil(err)
+	assert.Equal(int64(1), profile1.Version)
+
+	// second update is rejected
+	profile2.Bio = "bio2"
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", profile2.Id).
+		Set("bio").Value(&profile2)
+	assert.IsType(&ErrStaleObject{}, err)
+	assert.Equal(int64(0), profile2.Version)
+
+	row := UserProfile{}
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().
+		Filter("id=?", profile1.Id).One(&row))
+	assert.Equal("bio1", row.Bio)
+	assert.Equal(int64(1), row.Version)
+
+	// update again with the reloaded row
+	row.Bio = "bio3"
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", row.Id).
+		Set("bio").Value(&row)
+	assert.Nil(err)
+	assert.Equal(int64(2), row.Version)
+}
+
+func TestOptimisticLockingValues(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_PROFILE_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_PROFILE_TABLE))
+
+	_, err := tDatabase.T(USER_PROFILE_TABLE).Insert(&UserProfile{
+		Userid: TestUsers[0].Userid,
+	})
+	assert.Nil(err)
+	profile := UserProfile{}
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().
+		Filter("userid=?", TestUsers[0].Userid).One(&profile))
+
+	// Values and ValueMap increase version, so the row read before is stale
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", profile.Id).
+		Set("bio").Values("bio1")
+	assert.Nil(err)
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", profile.Id).
+		ValueMap(map[string]interface{}{"bio": "bio2"})
+	assert.Nil(err)
+	profile.Bio = "bio3"
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", profile.Id).
+		Value(&profile)
+	assert.IsType(&ErrStaleObject{}, err)
+
+	row := UserProfile{}
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().
+		Filter("id=?", profile.Id).One(&row))
+	assert.Equal("bio2", row.Bio)
+	assert.Equal(int64(2), row.Version)
+
+	// Values and ValueMap check the version given by Version only
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", row.Id).
+		Version(1).Set("bio").Values("bio4")
+	assert.Equal(&ErrStaleObject{Table: USER_PROFILE_TABLE, Version: 1}, err)
+	_, err = tDatabase.T(USER_PROFILE_TABLE).
+		Update("NOT version=?", row.Version).Set("bio").Values("bio4")
+	assert.Nil(err)
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", row.Id).
+		Version(row.Version).ValueMap(map[string]interface{}{"bio": "bio4"})
+	assert.Nil(err)
+	_, err = tDatabase.T(USER_TABLE).Update("id=?", 1).Version(1).
+		Set("nickname").Values("n")
+	assert.NotNil(err)
+
+	// Value updates the row read after them
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().
+		Filter("id=?", profile.Id).One(&row))
+	assert.Equal(int64(3), row.Version)
+	row.Bio = "bio5"
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", row.Id).Value(&row)
+	assert.Nil(err)
+	assert.Equal(int64(4), row.Version)
+}
@@ -0,0 +1,154 @@
+package dbx
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OpStats is the statistics of an operation on a table
+type OpStats struct {
+	Table  string
+	Op     string
+	Count  int
+	Errors int
+	Total  time.Duration
+	Max    time.Duration
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+}
+
+type statsKey struct {
+	table string
+	op    string
+}
+
+// max number of sampled durations per table and operation, percentiles are
+// estimated from the samples while counts, total and max are exact
+const statsReservoirSize = 1024
+
+type statsEntry struct {
+	count     int
+	errors    int
+	total     time.Duration
+	max       time.Duration
+	durations []time.Duration
+}
+
+// StatsCollector is an Interceptor collecting counts and latencies of calls
+// per table and operation in memory, e.g. for tests or debug pages. Memory is
+// bounded as percentiles are estimated from a fixed number of samples
+type StatsCollector struct {
+	mutex   sync.Mutex
+	entries map[statsKey]*statsEntry
+}
+
+// NewStatsCollector returns an empty stats collector, add it to database by
+// Database.Use
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{entries: map[statsKey]*statsEntry{}}
+}
+
+// Intercept implements Interceptor
+func (this *StatsCollector) Intercept(call *Call, next func() error) error {
+	start := time.Now()
+	err := next()
+	d := time.Since(start)
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	key := statsKey{table: call.Table, op: call.Op}
+	e, ok := this.entries[key]
+	if !ok {
+		e = &statsEntry{}
+		this.entries[key] = e
+	}
+	e.add(d)
+	if err != nil {
+		e.errors++
+	}
+	return err
+}
+
+// add adds duration d, keeping a uniform sample of all durations by
+// reservoir sampling
+func (this *statsEntry) add(d time.Duration) {
+	this.count++
+	this.total += d
+	if d > this.max {
+		this.max = d
+	}
+	if len(this.durations) < statsReservoirSize {
+		this.durations = append(this.durations, d)
+	} else if i := rand.Intn(this.count); i < statsReservoirSize {
+		this.durations[i] = d
+	}
+}
+
+// Get returns statistics of the given table and operation
+func (this *StatsCollector) Get(table, op string) OpStats {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	s := OpStats{Table: table, Op: op}
+	if e, ok := this.entries[statsKey{table: table, op: op}]; ok {
+		e.fill(&s)
+	}
+	return s
+}
+
+// All returns statistics of all tables and operations, sorted by table and
+// operation
+func (this *StatsCollector) All() []OpStats {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	all := make([]OpStats, 0, len(this.entries))
+	for k, e := range this.entries {
+		s := OpStats{Table: k.table, Op: k.op}
+		e.fill(&s)
+		all = append(all, s)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Table != all[j].Table {
+			return all[i].Table < all[j].Table
+		}
+		return all[i].Op < all[j].Op
+	})
+	return all
+}
+
+// Reset clears all statistics
+func (this *StatsCollector) Reset() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.entries = map[statsKey]*statsEntry{}
+}
+
+func (this *statsEntry) fill(s *OpStats) {
+	s.Count = this.count
+	s.Errors = this.errors
+	s.Total = this.total
+	s.Max = this.max
+	n := len(this.durations)
+	if n == 0 {
+		return
+	}
+
+	sorted := make([]time.Duration, n)
+	copy(sorted, this.durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	s.P50 = percentile(sorted, 50)
+	s.P90 = percentile(sorted, 90)
+	s.P99 = percentile(sorted, 99)
+}
+
+// percentile returns the p-th percentile of sorted durations by nearest rank
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,80 @@
+package dbx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsCollector(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+
+	stats := NewStatsCollector()
+	tDatabase.Use(stats)
+	defer tDatabase.ResetInterceptors()
+
+	for i := range TestUsers {
+		_, err := tDatabase.T(USER_TABLE).Insert(&TestUsers[i])
+		assert.Nil(err)
+	}
+	user := User{}
+	assert.Nil(tDatabase.T(USER_TABLE).SelectAll().One(&user))
+	assert.NotNil(tDatabase.T(USER_TABLE).Select("no_such_column").One(&user))
+
+	s := stats.Get(USER_TABLE, OP_INSERT)
+	assert.Equal(len(TestUsers), s.Count)
+	assert.Equal(0, s.Errors)
+	assert.True(s.P50 > 0)
+	assert.True(s.P50 <= s.P90 && s.P90 <= s.P99 && s.P99 <= s.Max)
+	assert.True(s.Max <= s.Total)
+
+	s = stats.Get(USER_TABLE, OP_SELECT)
+	assert.Equal(2, s.Count)
+	assert.Equal(1, s.Errors)
+
+	all := stats.All()
+	assert.Equal(2, len(all))
+	assert.Equal(OP_INSERT, all[0].Op)
+	assert.Equal(OP_SELECT, all[1].Op)
+
+	stats.Reset()
+	assert.Equal(0, stats.Get(USER_TABLE, OP_INSERT).Count)
+	assert.Empty(stats.All())
+}
+
+func TestStatsReservoir(t *testing.T) {
+	assert := assert.New(t)
+
+	e := &statsEntry{}
+	n := statsReservoirSize * 10
+	for i := 1; i <= n; i++ {
+		e.add(time.Duration(i))
+	}
+	assert.Equal(statsReservoirSize, len(e.durations))
+
+	s := OpStats{}
+	e.fill(&s)
+	assert.Equal(n, s.Count)
+	assert.Equal(time.Duration(n*(n+1)/2), s.Total)
+	assert.Equal(time.Duration(n), s.Max)
+	// estimated from uniform samples
+	assert.InDelta(n/2, int(s.P50), float64(n/10))
+	assert.True(s.P50 <= s.P90 && s.P90 <= s.P99 && s.P99 <= s.Max)
+}
+
+func TestPercentile(t *testing.T) {
+	assert := assert.New(t)
+
+	sorted := []time.Duration{}
+	for i := 1; i <= 10; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	assert.Equal(time.Duration(5), percentile(sorted, 50))
+	assert.Equal(time.Duration(9), percentile(sorted, 90))
+	assert.Equal(time.Duration(10), percentile(sorted, 99))
+	assert.Equal(time.Duration(1), percentile(sorted[:1], 50))
+}
@@ -0,0 +1,141 @@
+package dbx
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// format of DATETIME values stored in text columns
+const DATETIME_FORMAT = "2006-01-02 15:04:05"
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	nullTimeType   = reflect.TypeOf(sql.NullTime{})
+	nullInt64Type  = reflect.TypeOf(sql.NullInt64{})
+	nullStringType = reflect.TypeOf(sql.NullString{})
+)
+
+// isNullableType checks if a field of type t can hold NULL
+func isNullableType(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr || reflect.PtrTo(t).Implements(scannerType)
+}
+
+// isIntegerSQL checks if a column definition is an integer type
+func isIntegerSQL(def string) bool {
+	def = strings.ToUpper(strings.TrimSpace(def))
+	for _, t := range []string{"INT", "BIGINT", "INTEGER", "MEDIUMINT"} {
+		if def == t || strings.HasPrefix(def, t+" ") ||
+			strings.HasPrefix(def, t+"(") {
+			return true
+		}
+	}
+	return false
+}
+
+// timestampValue returns the SQL value of given time for column c whose field
+// type is t. Integer fields and text fields of an integer column for the
+// driver get unix seconds, time fields get time.Time and other text fields
+// get DATETIME_FORMAT text
+func timestampValue(c Column, t reflect.Type, driver string, now time.Time) (
+	interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType, nullTimeType:
+		return now, nil
+	case nullInt64Type:
+		return now.Unix(), nil
+	case nullStringType:
+		if isIntegerSQL(c.SQL(driver)) {
+			return now.Unix(), nil
+		}
+		return now.Format(DATETIME_FORMAT), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32,
+		reflect.Uint64:
+		return now.Unix(), nil
+	case reflect.String:
+		if isIntegerSQL(c.SQL(driver)) {
+			return now.Unix(), nil
+		}
+		return now.Format(DATETIME_FORMAT), nil
+	}
+	return nil, fmt.Errorf("%s can't be used as a timestamp", t)
+}
+
+// setTimestamp sets now to the timestamp column c of row, it returns the SQL
+// value of the column
+func setTimestamp(row reflect.Value, c Column, driver string, now time.Time) (
+	interface{}, error) {
+	field := row.Field(c.Index)
+	v, err := timestampValue(c, field.Type(), driver, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := assignValue(field, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// stampInsert sets timestamp columns of the row to be inserted, the create
+// time is only set if it's zero
+func (this *SQLExecutor) stampInsert(row reflect.Value) error {
+	now := this.database.now()
+	for _, c := range this.table.orderedColumns() {
+		if c.IsAutoUpdateTime ||
+			(c.IsAutoCreateTime && row.Field(c.Index).IsZero()) {
+			if _, err := setTimestamp(row, c, this.driver(), now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// driver returns driver name of executor
+func (this *SQLExecutor) driver() string {
+	if this.database == nil {
+		return ""
+	}
+	return this.database.driver
+}
+
+// updateTimestamps returns auto update time columns of table which are not in
+// the given columns, with their SQL values
+func (this *SQLUpdater) updateTimestamps(cols []string) (
+	[]string, []interface{}, error) {
+	names := []string{}
+	vals := []interface{}{}
+	now := this.database.now()
+	driver := this.executor().driver()
+	for _, c := range this.table.orderedColumns() {
+		if !c.IsAutoUpdateTime || containsString(cols, c.Name) {
+			continue
+		}
+		v, err := timestampValue(c, this.table.rowType.Field(c.Index).Type,
+			driver, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		names = append(names, c.Name)
+		vals = append(vals, v)
+	}
+	return names, vals, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package dbx
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoTimestamps(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_PROFILE_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_PROFILE_TABLE))
+
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	tDatabase.SetClock(func() time.Time { return now })
+	defer tDatabase.SetClock(nil)
+
+	// the update time is formatted by the column type of driver
+	updatedAt := strconv.FormatInt(now.Unix(), 10)
+	if tDatabase.DriverName() == DRIVER_MYSQL {
+		updatedAt = now.Format(DATETIME_FORMAT)
+	}
+
+	// insert sets both create and update time
+	profile := UserProfile{Userid: TestUsers[0].Userid}
+	_, err := tDatabase.T(USER_PROFILE_TABLE).Insert(&profile)
+	assert.Nil(err)
+	assert.Equal(now.Unix(), profile.CreatedAt)
+	assert.Equal(updatedAt, profile.UpdatedAt)
+
+	// explicit create time is kept
+	profile2 := UserProfile{Userid: TestUsers[1].Userid, CreatedAt: 100}
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Insert(&profile2)
+	assert.Nil(err)
+	assert.Equal(int64(100), profile2.CreatedAt)
+
+	row := UserProfile{}
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().
+		Filter("userid=?", profile.Userid).One(&row))
+	assert.Equal(now.Unix(), row.CreatedAt)
+	assert.Equal(updatedAt, row.UpdatedAt)
+
+	// update with row
+	now = now.Add(time.Hour)
+	updatedAt2 := strconv.FormatInt(now.Unix(), 10)
+	if tDatabase.DriverName() == DRIVER_MYSQL {
+		updatedAt2 = now.Format(DATETIME_FORMAT)
+	}
+	row.Bio = "bio"
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", row.Id).Set("bio").
+		Value(&row)
+	assert.Nil(err)
+	assert.Equal(updatedAt2, row.UpdatedAt)
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().
+		Filter("id=?", row.Id).One(&row))
+	assert.Equal(now.Unix(), row.CreatedAt+3600)
+	assert.Equal(updatedAt2, row.UpdatedAt)
+
+	// update with values and value map
+	now = now.Add(time.Hour)
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", row.Id).Set("bio").
+		Values("bio2")
+	assert.Nil(err)
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().
+		Filter("id=?", row.Id).One(&row))
+	assert.Equal("bio2", row.Bio)
+	assert.NotEqual(updatedAt2, row.UpdatedAt)
+
+	_, err = tDatabase.T(USER_PROFILE_TABLE).Update("id=?", row.Id).
+		ValueMap(map[string]interface{}{"bio": "bio3", "updated_at": "0"})
+	assert.Nil(err)
+	assert.Nil(tDatabase.T(USER_PROFILE_TABLE).SelectAll().
+		Filter("id=?", row.Id).One(&row))
+	assert.Equal("bio3", row.Bio)
+	assert.Equal("0", row.UpdatedAt)
+}
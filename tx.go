@@ -0,0 +1,277 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+)
+
+// TxOptions defines options of Database.InTx
+type TxOptions struct {
+	// Isolation is the isolation level of transaction, see BeginTx
+	Isolation sql.IsolationLevel
+	// ReadOnly makes a read-only transaction
+	ReadOnly bool
+	// MaxRetries is the max number of retries if transaction fails with a
+	// retryable error, 0 means no retry
+	MaxRetries int
+	// Backoff returns the delay before the n-th (starts from 1) retry, nil
+	// means retrying immediately
+	Backoff func(n int) time.Duration
+	// Retryable checks if an error is retryable, IsRetryableError is used if
+	// it's nil
+	Retryable func(err error) bool
+}
+
+func (this *TxOptions) retryable(err error) bool {
+	if this.Retryable != nil {
+		return this.Retryable(err)
+	}
+	return IsRetryableError(err)
+}
+
+// IsRetryableError checks if err is a transient error of concurrent
+// transactions, i.e. SQLite busy/locked and MySQL deadlock/lock wait timeout
+func IsRetryableError(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy ||
+			sqliteErr.Code == sqlite3.ErrLocked
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+	return false
+}
+
+// ExponentialBackoff returns a backoff which doubles the delay from base on
+// every retry, up to max
+func ExponentialBackoff(base, max time.Duration) func(n int) time.Duration {
+	return func(n int) time.Duration {
+		d := base
+		for i := 1; i < n && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// BeginTx begins a transaction with the given isolation level and read-only
+// flag, nil opts means the default options of driver.
+// SQLite driver ignores the options, so they are emulated: a read-only
+// transaction sets PRAGMA query_only on its connection until it ends, LevelSerializable
+// begins with BEGIN IMMEDIATE and LevelLinearizable begins with BEGIN
+// EXCLUSIVE to acquire the write lock upfront instead of failing with busy
+// when upgrading a read lock later
+func (this *Database) BeginTx(ctx context.Context, opts *sql.TxOptions) (
+	*Transaction, error) {
+	if this.db == nil {
+		return nil, fmt.Errorf("no opened database")
+	}
+
+	// emulated SQLite transactions run on a pinned connection, so PRAGMA
+	// query_only can be reset before the connection is returned to pool
+	// however the transaction ends, and the lock can be acquired on it
+	readOnly, lock := false, ""
+	if this.driver == DRIVER_SQLITE3 && opts != nil {
+		readOnly = opts.ReadOnly
+		if !readOnly {
+			lock = sqliteLock(opts.Isolation)
+		}
+	}
+	var conn *sql.Conn
+	var sqlTx *sql.Tx
+	call := &Call{Ctx: ctx, Op: OP_BEGIN}
+	err := this.intercept(call, func() error {
+		ctx := call.Ctx
+		var err error
+		if !readOnly && lock == "" {
+			sqlTx, err = this.db.BeginTx(ctx, opts)
+			return err
+		}
+
+		if conn, err = this.db.Conn(ctx); err != nil {
+			return err
+		}
+		if readOnly {
+			_, err = conn.ExecContext(ctx, "PRAGMA query_only=1")
+		}
+		if err == nil {
+			sqlTx, err = conn.BeginTx(ctx, opts)
+			if err == nil && lock != "" {
+				if err = beginWithLock(ctx, conn, lock); err != nil {
+					sqlTx.Rollback()
+				}
+			}
+		}
+		if err != nil {
+			if readOnly {
+				resetQueryOnly(conn)
+			} else {
+				conn.Close()
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the context replaced by interceptors is carried by transaction
+	ctx = call.Ctx
+	tx := &Transaction{db: this, tx: sqlTx}
+	tx.ctx = context.WithValue(ctx, txContextKey{}, tx)
+	if readOnly {
+		tx.conn = conn
+		tx.stopRelease = context.AfterFunc(ctx, tx.releaseConn)
+	} else if conn != nil {
+		// closing the connection waits for the transaction to end, then it's
+		// returned to pool as is
+		go conn.Close()
+	}
+	return tx, nil
+}
+
+// sqliteLock returns the statement beginning a SQLite transaction of
+// isolation level, empty if the default deferred transaction is enough
+func sqliteLock(level sql.IsolationLevel) string {
+	switch level {
+	case sql.LevelSerializable:
+		return "BEGIN IMMEDIATE"
+	case sql.LevelLinearizable:
+		return "BEGIN EXCLUSIVE"
+	}
+	return ""
+}
+
+// beginWithLock ends the deferred transaction begun by driver on conn and
+// begins again by statement begin. Nothing is done in the deferred
+// transaction, and driver's Commit and Rollback still work on the new one.
+// Statements run on the driver connection, so they're never logged,
+// intercepted or recorded by dry run
+func beginWithLock(ctx context.Context, conn *sql.Conn, begin string) error {
+	return conn.Raw(func(dc interface{}) error {
+		execer, ok := dc.(driver.ExecerContext)
+		if !ok {
+			return fmt.Errorf("driver connection can't exec %s", begin)
+		}
+		if _, err := execer.ExecContext(ctx, "COMMIT", nil); err != nil {
+			return err
+		}
+		_, err := execer.ExecContext(ctx, begin, nil)
+		return err
+	})
+}
+
+// releaseConn resets PRAGMA query_only of the pinned connection of read-only
+// SQLite transaction and returns it to pool. It's called when the transaction
+// is committed or rolled back, or its context is done, as database/sql rolls
+// back the transaction by itself then. Closing the connection waits for the
+// transaction to end
+func (this *Transaction) releaseConn() {
+	this.releaseOnce.Do(func() {
+		if this.stopRelease != nil {
+			this.stopRelease()
+		}
+		resetQueryOnly(this.conn)
+	})
+}
+
+// resetQueryOnly resets PRAGMA query_only of conn and closes it, conn is
+// discarded instead of returning to pool if the pragma can't be reset
+func resetQueryOnly(conn *sql.Conn) {
+	_, err := conn.ExecContext(context.Background(), "PRAGMA query_only=0")
+	if err != nil {
+		conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	}
+	conn.Close()
+}
+
+type txContextKey struct{}
+
+// TxFromContext returns the transaction carried by ctx, nil if there is no
+// transaction. The context of transaction can be get by Transaction.Context
+func TxFromContext(ctx context.Context) *Transaction {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(txContextKey{}).(*Transaction)
+	return tx
+}
+
+// Context returns the context of transaction, which carries the transaction
+// itself. Passing it to Database.InTx makes a nested transaction
+func (this *Transaction) Context() context.Context {
+	if this.ctx == nil {
+		this.ctx = context.WithValue(context.Background(), txContextKey{}, this)
+	}
+	return this.ctx
+}
+
+// InTx runs fn in a transaction, the transaction is committed if fn returns
+// nil, otherwise it's rolled back. If fn panics, the transaction is rolled
+// back and the panic is propagated. The whole transaction is retried if it
+// fails with a retryable error and opts allows.
+// If ctx already carries a transaction of the database, fn runs in a nested
+// transaction of savepoint instead and opts is ignored
+func (this *Database) InTx(ctx context.Context, opts *TxOptions,
+	fn func(tx *Transaction) error) error {
+	if tx := TxFromContext(ctx); tx != nil && tx.db == this {
+		return tx.InTx(fn)
+	}
+
+	if opts == nil {
+		opts = &TxOptions{}
+	}
+
+	for n := 1; ; n++ {
+		err := this.runTx(ctx, opts, fn)
+		if err == nil || n > opts.MaxRetries || !opts.retryable(err) {
+			return err
+		}
+
+		if opts.Backoff != nil {
+			timer := time.NewTimer(opts.Backoff(n))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+func (this *Database) runTx(ctx context.Context, opts *TxOptions,
+	fn func(tx *Transaction) error) error {
+	tx, err := this.BeginTx(ctx, &sql.TxOptions{
+		Isolation: opts.Isolation, ReadOnly: opts.ReadOnly,
+	})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
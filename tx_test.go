@@ -0,0 +1,211 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInTx(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+
+	// commit
+	assert.Nil(tDatabase.InTx(ctx, nil, func(tx *Transaction) error {
+		_, err := tx.T(USER_TABLE).Insert(&TestUsers[0])
+		return err
+	}))
+	n, err := tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(1, n)
+
+	// rollback on error
+	errAbort := fmt.Errorf("abort")
+	assert.Equal(errAbort, tDatabase.InTx(ctx, nil, func(tx *Transaction) error {
+		if _, err := tx.T(USER_TABLE).Insert(&TestUsers[1]); err != nil {
+			return err
+		}
+		return errAbort
+	}))
+	n, err = tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(1, n)
+
+	// rollback on panic
+	assert.PanicsWithValue("panic", func() {
+		tDatabase.InTx(ctx, nil, func(tx *Transaction) error {
+			tx.T(USER_TABLE).Insert(&TestUsers[1])
+			panic("panic")
+		})
+	})
+	n, err = tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(1, n)
+
+	// retry on retryable errors
+	attempts := 0
+	delays := []time.Duration{}
+	opts := &TxOptions{
+		MaxRetries: 2,
+		Backoff: func(n int) time.Duration {
+			d := ExponentialBackoff(time.Millisecond, 10*time.Millisecond)(n)
+			delays = append(delays, d)
+			return d
+		},
+	}
+	assert.Nil(tDatabase.InTx(ctx, opts, func(tx *Transaction) error {
+		attempts++
+		if _, err := tx.T(USER_TABLE).Insert(&TestUsers[1]); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return sqlite3.Error{Code: sqlite3.ErrBusy}
+		}
+		return nil
+	}))
+	assert.Equal(3, attempts)
+	assert.Equal([]time.Duration{time.Millisecond, 2 * time.Millisecond}, delays)
+	n, err = tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(2, n)
+
+	// give up after max retries
+	attempts = 0
+	err = tDatabase.InTx(ctx, &TxOptions{MaxRetries: 1},
+		func(tx *Transaction) error {
+			attempts++
+			return &mysql.MySQLError{Number: 1213}
+		})
+	assert.NotNil(err)
+	assert.Equal(2, attempts)
+
+	// no retry on other errors
+	attempts = 0
+	err = tDatabase.InTx(ctx, &TxOptions{MaxRetries: 3},
+		func(tx *Transaction) error {
+			attempts++
+			return errAbort
+		})
+	assert.Equal(errAbort, err)
+	assert.Equal(1, attempts)
+}
+
+func TestIsRetryableError(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsRetryableError(sqlite3.Error{Code: sqlite3.ErrBusy}))
+	assert.True(IsRetryableError(
+		fmt.Errorf("wrapped: %w", sqlite3.Error{Code: sqlite3.ErrLocked})))
+	assert.False(IsRetryableError(sqlite3.Error{Code: sqlite3.ErrConstraint}))
+	assert.True(IsRetryableError(&mysql.MySQLError{Number: 1205}))
+	assert.False(IsRetryableError(&mysql.MySQLError{Number: 1062}))
+	assert.False(IsRetryableError(fmt.Errorf("error")))
+}
+
+func TestBeginTxOptions(t *testing.T) {
+	if tDatabase.DriverName() != DRIVER_SQLITE3 {
+		t.Skip("emulation of transaction options is only for sqlite")
+	}
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+	tDatabase.DB().SetMaxOpenConns(1)
+	defer tDatabase.DB().SetMaxOpenConns(0)
+
+	// read-only transaction
+	tx, err := tDatabase.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	assert.Nil(err)
+	_, err = tx.T(USER_TABLE).Insert(&TestUsers[0])
+	assert.NotNil(err)
+	_, err = tx.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Nil(tx.Commit())
+
+	// the connection is writable again
+	_, err = tDatabase.T(USER_TABLE).Insert(&TestUsers[0])
+	assert.Nil(err)
+	err = tDatabase.InTx(ctx, &TxOptions{ReadOnly: true},
+		func(tx *Transaction) error {
+			_, err := tx.T(USER_TABLE).Insert(&TestUsers[1])
+			return err
+		})
+	assert.NotNil(err)
+	_, err = tDatabase.T(USER_TABLE).Insert(&TestUsers[1])
+	assert.Nil(err)
+
+	// the connection is writable after the context is canceled
+	cancelCtx, cancel := context.WithCancel(ctx)
+	tx, err = tDatabase.BeginTx(cancelCtx, &sql.TxOptions{ReadOnly: true})
+	assert.Nil(err)
+	cancel()
+	_, err = tDatabase.T(USER_TABLE).Insert(&TestUsers[2])
+	assert.Nil(err)
+	assert.Nil(tDatabase.T(USER_TABLE).Delete("userid=?", TestUsers[2].Userid))
+	assert.NotNil(tx.Rollback())
+
+	// sql.Tx isn't exposed, so the connection can't be kept by ending it
+	tx, err = tDatabase.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	assert.Nil(err)
+	assert.Nil(tx.Tx())
+	assert.Nil(tx.Commit())
+	_, err = tDatabase.T(USER_TABLE).Insert(&TestUsers[2])
+	assert.Nil(err)
+	assert.Nil(tDatabase.T(USER_TABLE).Delete("userid=?", TestUsers[2].Userid))
+	tx, err = tDatabase.BeginTx(ctx, nil)
+	assert.Nil(err)
+	assert.NotNil(tx.Tx())
+	assert.Nil(tx.Rollback())
+
+	// serializable transaction holds the write lock from beginning
+	other, err := sql.Open(DRIVER_SQLITE3, TEST_DB_FILE+"?_busy_timeout=0")
+	assert.Nil(err)
+	defer other.Close()
+
+	tx, err = tDatabase.BeginTx(ctx,
+		&sql.TxOptions{Isolation: sql.LevelSerializable})
+	assert.Nil(err)
+	_, err = other.Exec("INSERT INTO user(userid) VALUES('other')")
+	assert.True(IsRetryableError(err))
+	_, err = tx.T(USER_TABLE).Insert(&TestUsers[2])
+	assert.Nil(err)
+	assert.Nil(tx.Commit())
+
+	_, err = other.Exec("INSERT INTO user(userid) VALUES('other')")
+	assert.Nil(err)
+	n, err := tDatabase.T(USER_TABLE).CountAll()
+	assert.Nil(err)
+	assert.Equal(4, n)
+
+	// the lock is acquired even in dry-run mode, and isn't logged
+	events := []QueryEvent{}
+	tDatabase.SetLogger(LoggerFunc(func(ev *QueryEvent) {
+		events = append(events, *ev)
+	}))
+	defer tDatabase.SetLogger(nil)
+	tDatabase.SetDryRun(true)
+	tx, err = tDatabase.BeginTx(ctx,
+		&sql.TxOptions{Isolation: sql.LevelLinearizable})
+	tDatabase.SetDryRun(false)
+	assert.Nil(err)
+	_, err = other.Exec("INSERT INTO user(userid) VALUES('other2')")
+	assert.True(IsRetryableError(err))
+	assert.Nil(tx.Rollback())
+	assert.Equal(0, len(tDatabase.DryRunStatements()))
+	for _, ev := range events {
+		assert.NotContains(ev.SQL, "BEGIN")
+		assert.NotContains(ev.SQL, "COMMIT")
+	}
+	_, err = other.Exec("INSERT INTO user(userid) VALUES('other2')")
+	assert.Nil(err)
+}
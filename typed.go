@@ -0,0 +1,265 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"net/url"
+	"reflect"
+)
+
+// TypedTable is a type-safe handle of a table whose rows are T. It's a thin
+// layer over SQLExecutor and SQLSelector, use Executor for the untyped API
+type TypedTable[T any] struct {
+	q    Querier
+	name string
+	ctx  context.Context
+}
+
+// TableOf returns a typed handle of the registered table in database or
+// transaction q, T must be the struct type registered for the table:
+//
+//	users := dbx.TableOf[User](db, "user")
+//	user, err := users.Filter("userid=?", "u1").One()
+//
+// The name TableOf is used as Table is the registered table struct
+func TableOf[T any](q Querier, name string) *TypedTable[T] {
+	return &TypedTable[T]{q: q, name: name}
+}
+
+// WithContext returns a handle whose statements run with ctx
+func (this *TypedTable[T]) WithContext(ctx context.Context) *TypedTable[T] {
+	t := *this
+	t.ctx = ctx
+	return &t
+}
+
+// Executor returns the untyped executor of table
+func (this *TypedTable[T]) Executor() *SQLExecutor {
+	e := this.q.T(this.name)
+	if this.ctx != nil {
+		e = e.WithContext(this.ctx)
+	}
+	if e.err != nil {
+		return e
+	}
+
+	rowType := reflect.TypeOf((*T)(nil)).Elem()
+	if rowType != e.table.rowType {
+		e.err = fmt.Errorf("%s table has rows of %s, not %s", this.name,
+			e.table.rowType, rowType)
+	}
+	return e
+}
+
+// Insert inserts row to table
+func (this *TypedTable[T]) Insert(row *T) (sql.Result, error) {
+	return this.Executor().Insert(row)
+}
+
+// Replace replaces with row
+func (this *TypedTable[T]) Replace(row *T) (sql.Result, error) {
+	return this.Executor().Replace(row)
+}
+
+// Update updates row to table by filter, all columns are updated if no
+// column is given
+func (this *TypedTable[T]) Update(row *T, where string, args ...interface{}) (
+	sql.Result, error) {
+	return this.Executor().Update(where, args...).Value(row)
+}
+
+// Delete deletes rows by filter
+func (this *TypedTable[T]) Delete(where string, args ...interface{}) error {
+	return this.Executor().Delete(where, args...)
+}
+
+// DeleteRow deletes row by its primary key
+func (this *TypedTable[T]) DeleteRow(row *T) error {
+	return this.Executor().DeleteRow(row)
+}
+
+// Count counts rows by filter
+func (this *TypedTable[T]) Count(where string, args ...interface{}) (int,
+	error) {
+	return this.Executor().Count(where, args...)
+}
+
+// SelectAll selects all columns of table
+func (this *TypedTable[T]) SelectAll() *TypedSelector[T] {
+	return &TypedSelector[T]{s: this.Executor().SelectAll()}
+}
+
+// Select selects the given columns of table, other fields of rows are zero
+func (this *TypedTable[T]) Select(cols ...string) *TypedSelector[T] {
+	return &TypedSelector[T]{s: this.Executor().Select(cols...)}
+}
+
+// Filter selects all columns of table by filter
+func (this *TypedTable[T]) Filter(where string,
+	args ...interface{}) *TypedSelector[T] {
+	return this.SelectAll().Filter(where, args...)
+}
+
+// All selects all rows of table
+func (this *TypedTable[T]) All() ([]T, error) {
+	return this.SelectAll().All()
+}
+
+// TypedSelector is a type-safe SQLSelector whose rows are T
+type TypedSelector[T any] struct {
+	s *SQLSelector
+}
+
+// Selector returns the untyped selector
+func (this *TypedSelector[T]) Selector() *SQLSelector {
+	return this.s
+}
+
+// Filter set filters for select
+func (this *TypedSelector[T]) Filter(where string,
+	args ...interface{}) *TypedSelector[T] {
+	this.s.Filter(where, args...)
+	return this
+}
+
+// Asc sorts given columns by asc
+func (this *TypedSelector[T]) Asc(cols ...string) *TypedSelector[T] {
+	this.s.Asc(cols...)
+	return this
+}
+
+// Desc sorts given columns by desc
+func (this *TypedSelector[T]) Desc(cols ...string) *TypedSelector[T] {
+	this.s.Desc(cols...)
+	return this
+}
+
+// OrderBy sorts given columns by their own orders, a column prefixed with
+// "-" is sorted by desc
+func (this *TypedSelector[T]) OrderBy(cols ...string) *TypedSelector[T] {
+	this.s.OrderBy(cols...)
+	return this
+}
+
+// FromQuery adds filters, sorting and pagination of URL query parameters
+// limited by rules
+func (this *TypedSelector[T]) FromQuery(values url.Values,
+	rules *QueryRules) *TypedSelector[T] {
+	this.s.FromQuery(values, rules)
+	return this
+}
+
+// NullAsZero scans NULL columns as the zero value of struct fields
+func (this *TypedSelector[T]) NullAsZero() *TypedSelector[T] {
+	this.s.NullAsZero()
+	return this
+}
+
+// Limit sets limit of query
+func (this *TypedSelector[T]) Limit(n int) *TypedSelector[T] {
+	this.s.Limit(n)
+	return this
+}
+
+// Offset sets offset of query
+func (this *TypedSelector[T]) Offset(n int) *TypedSelector[T] {
+	this.s.Offset(n)
+	return this
+}
+
+// Preload loads the given relations of selected rows
+func (this *TypedSelector[T]) Preload(relations ...string) *TypedSelector[T] {
+	this.s.Preload(relations...)
+	return this
+}
+
+// ToSQL returns the select statement and its arguments without executing it
+func (this *TypedSelector[T]) ToSQL() (string, []interface{}, error) {
+	return this.s.ToSQL()
+}
+
+// Count counts rows by the filter of selector
+func (this *TypedSelector[T]) Count() (int, error) {
+	return this.s.Count()
+}
+
+// One selects one row, sql.ErrNoRows is returned if there's no row
+func (this *TypedSelector[T]) One() (*T, error) {
+	row := new(T)
+	if err := this.s.One(row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// All selects all rows
+func (this *TypedSelector[T]) All() ([]T, error) {
+	rows := []T{}
+	if err := this.s.All(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Iter returns an iterator of rows which are scanned one by one instead of
+// being loaded to a slice. An error stops the iteration:
+//
+//	for user, err := range users.SelectAll().Iter() {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Rows are read while the query is open, so a transaction can't run other
+// statements in the loop. Relations can't be preloaded by Iter
+func (this *TypedSelector[T]) Iter() iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		s := this.s
+		if s.err != nil {
+			yield(nil, s.err)
+			return
+		}
+		if len(s.preloads) > 0 {
+			yield(nil, fmt.Errorf("relations can't be preloaded by Iter"))
+			return
+		}
+
+		indexes := s.columnIndexes()
+		refs := make([]interface{}, len(indexes))
+		stopped := false
+		q := s.buildSQL()
+		err := s.query(s.table.Name, OP_SELECT, q, s.filter.args,
+			func(rs *sql.Rows) (int64, error) {
+				n := int64(0)
+				for rs.Next() {
+					row := new(T)
+					rowVal := reflect.ValueOf(row).Elem()
+					if s.nullAsZero {
+						newRowScanner(rowVal, indexes, true).refs(refs)
+					} else {
+						for k, j := range indexes {
+							refs[k] = rowVal.Field(j).Addr().Interface()
+						}
+					}
+					if err := rs.Scan(refs...); err != nil {
+						return n, err
+					}
+					n++
+					if err := afterFind(s.executor(), rowVal); err != nil {
+						return n, err
+					}
+					if !yield(row, nil) {
+						stopped = true
+						break
+					}
+				}
+				return n, nil
+			})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package dbx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedTable(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+
+	users := TableOf[User](tDatabase, USER_TABLE)
+	for i := range TestUsers {
+		u := TestUsers[i]
+		_, err := users.Insert(&u)
+		assert.Nil(err)
+	}
+
+	all, err := users.All()
+	assert.Nil(err)
+	assert.Equal(len(TestUsers), len(all))
+
+	u, err := users.Filter("userid=?", TestUsers[1].Userid).One()
+	assert.Nil(err)
+	assert.Equal(TestUsers[1].Nickname, u.Nickname)
+
+	u.Nickname = "typed"
+	_, err = users.WithContext(context.Background()).Update(u, "id=?", u.Id)
+	assert.Nil(err)
+	n, err := users.Count("nickname=?", "typed")
+	assert.Nil(err)
+	assert.Equal(1, n)
+
+	desc, err := users.Select("id", "userid").Desc("id").Limit(2).All()
+	assert.Nil(err)
+	assert.Equal(2, len(desc))
+	assert.True(desc[0].Id > desc[1].Id)
+	assert.Equal("", desc[0].Nickname)
+
+	assert.Nil(users.DeleteRow(u))
+	_, err = users.Filter("id=?", u.Id).One()
+	assert.NotNil(err)
+
+	// the registered row type must be used
+	_, err = TableOf[UserLogin](tDatabase, USER_TABLE).All()
+	assert.NotNil(err)
+	_, err = TableOf[User](tDatabase, "no_such_table").All()
+	assert.NotNil(err)
+
+	// typed handle in transaction
+	tx, err := tDatabase.Begin()
+	assert.Nil(err)
+	assert.Nil(TableOf[User](tx, USER_TABLE).Delete("id=?", all[0].Id))
+	assert.Nil(tx.Rollback())
+	n, err = users.Count("id=?", all[0].Id)
+	assert.Nil(err)
+	assert.Equal(1, n)
+}
+
+func TestTypedSelectorIter(t *testing.T) {
+	assert := assert.New(t)
+
+	tDatabase.DropTable(USER_TABLE)
+	assert.Nil(tDatabase.CreateTable(USER_TABLE))
+
+	users := TableOf[User](tDatabase, USER_TABLE)
+	for i := range TestUsers {
+		u := TestUsers[i]
+		_, err := users.Insert(&u)
+		assert.Nil(err)
+	}
+
+	ids := []int64{}
+	for u, err := range users.SelectAll().Asc("id").Iter() {
+		assert.Nil(err)
+		ids = append(ids, u.Id)
+	}
+	assert.Equal(len(TestUsers), len(ids))
+
+	// break stops scanning
+	count := 0
+	for _, err := range users.SelectAll().Iter() {
+		assert.Nil(err)
+		count++
+		break
+	}
+	assert.Equal(1, count)
+
+	// errors are yielded
+	for u, err := range TableOf[User](tDatabase, "no_such_table").
+		SelectAll().Iter() {
+		assert.Nil(u)
+		assert.NotNil(err)
+	}
+	for _, err := range users.SelectAll().Filter("no_such_column=?", 1).Iter() {
+		assert.NotNil(err)
+	}
+}
@@ -0,0 +1,200 @@
+package dbx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SchemaMismatch is a difference between a registered table and the table
+// in database found by Verify
+type SchemaMismatch struct {
+	Table string
+	// Column is empty if the mismatch isn't of a column
+	Column  string
+	Message string
+}
+
+func (this *SchemaMismatch) Error() string {
+	if this.Column == "" {
+		return this.Table + ": " + this.Message
+	}
+	return this.Table + "." + this.Column + ": " + this.Message
+}
+
+// SchemaError reports all mismatches found by Verify
+type SchemaError struct {
+	Mismatches []*SchemaMismatch
+}
+
+func (this *SchemaError) Error() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "schema of database has %d mismatches with registered "+
+		"tables:", len(this.Mismatches))
+	for _, m := range this.Mismatches {
+		b.WriteString("\n  " + m.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the mismatches for errors.As
+func (this *SchemaError) Unwrap() []error {
+	errs := make([]error, len(this.Mismatches))
+	for i, m := range this.Mismatches {
+		errs[i] = m
+	}
+	return errs
+}
+
+// Verify checks that every registered table exists in database, its columns
+// exist with types compatible to the struct fields, and its primary key and
+// declared indexes match. It's run before serving to catch migrations which
+// haven't run. All mismatches are returned in a *SchemaError, other errors
+// are returned if the schema can't be read
+func (this *Database) Verify() error {
+	if this.db == nil {
+		return fmt.Errorf("no opened database")
+	}
+
+	existing, err := this.existingTables()
+	if err != nil {
+		return err
+	}
+	exists := map[string]bool{}
+	for _, name := range existing {
+		exists[strings.ToLower(name)] = true
+	}
+
+	errs := &SchemaError{}
+	for _, name := range this.TableNames() {
+		if !exists[strings.ToLower(name)] {
+			errs.Mismatches = append(errs.Mismatches, &SchemaMismatch{
+				Table: name, Message: "table doesn't exist",
+			})
+			continue
+		}
+		schemas, err := this.Introspect(name)
+		if err != nil {
+			return err
+		}
+		t := this.tables[name]
+		errs.Mismatches = append(errs.Mismatches, t.verify(&schemas[0])...)
+	}
+
+	if len(errs.Mismatches) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// verify compares table with its schema in database
+func (this *Table) verify(s *TableSchema) []*SchemaMismatch {
+	mismatches := []*SchemaMismatch{}
+	add := func(col, format string, args ...interface{}) {
+		mismatches = append(mismatches, &SchemaMismatch{
+			Table: this.Name, Column: col, Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, c := range this.orderedColumns() {
+		sc := s.Column(c.Name)
+		if sc == nil {
+			add(c.Name, "column doesn't exist")
+			continue
+		}
+		t := this.rowType.Field(c.Index).Type
+		if !compatibleType(t, sc.Type) {
+			add(c.Name, "column type %s isn't compatible with field type %s",
+				sc.Type, t)
+		}
+		if c.IsPrimaryKey != sc.IsPrimaryKey {
+			if c.IsPrimaryKey {
+				add(c.Name, "column isn't primary key")
+			} else {
+				add(c.Name, "column is primary key but not declared")
+			}
+		}
+	}
+
+	for _, index := range this.Indexes {
+		var found *TableIndex
+		for i := range s.Indexes {
+			if strings.EqualFold(s.Indexes[i].Name, index.Name) {
+				found = &s.Indexes[i]
+			}
+		}
+		switch {
+		case found == nil:
+			add("", "index %s doesn't exist", index.Name)
+		case !strings.EqualFold(strings.Join(found.Columns, ","),
+			strings.Join(index.Columns, ",")):
+			add("", "index %s is on (%s), not (%s)", index.Name,
+				strings.Join(found.Columns, ","), strings.Join(index.Columns, ","))
+		case found.IsUnique != index.IsUnique:
+			if index.IsUnique {
+				add("", "index %s isn't unique", index.Name)
+			} else {
+				add("", "index %s is unique but not declared", index.Name)
+			}
+		}
+	}
+	return mismatches
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// compatibleType checks if values of a column of the declared type can be
+// scanned into and written from a field of type t
+func compatibleType(t reflect.Type, colType string) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case nullStringType:
+		t = reflect.TypeOf("")
+	case nullInt64Type, reflect.TypeOf(sql.NullInt32{}):
+		t = reflect.TypeOf(int64(0))
+	case reflect.TypeOf(sql.NullFloat64{}):
+		t = reflect.TypeOf(float64(0))
+	case reflect.TypeOf(sql.NullBool{}):
+		t = reflect.TypeOf(false)
+	case nullTimeType:
+		t = timeType
+	}
+
+	ct := strings.ToLower(colType)
+	isInt := strings.Contains(ct, "int")
+	isReal := strings.Contains(ct, "real") || strings.Contains(ct, "floa") ||
+		strings.Contains(ct, "doub") || strings.HasPrefix(ct, "decimal") ||
+		strings.HasPrefix(ct, "numeric")
+	isBool := strings.HasPrefix(ct, "bool") || strings.HasPrefix(ct, "bit")
+	isTime := strings.Contains(ct, "date") || strings.Contains(ct, "time") ||
+		strings.HasPrefix(ct, "year")
+	isText := strings.Contains(ct, "char") || strings.Contains(ct, "text") ||
+		strings.Contains(ct, "clob")
+
+	switch {
+	case ct == "":
+		// sqlite column without type affinity accepts any value
+		return true
+	case t == timeType:
+		// time is stored as unix time or text by sqlite
+		return isTime || isInt || isText
+	case t.Kind() == reflect.String:
+		// timestamps of string fields can be stored as unix time
+		return !strings.Contains(ct, "blob") && !strings.Contains(ct, "binary")
+	case t.Kind() == reflect.Bool:
+		return isBool || isInt
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return isInt || isBool || strings.HasPrefix(ct, "numeric")
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return isReal || isInt
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return true
+	}
+
+	// custom types are converted by their Scan and Value methods
+	return reflect.PtrTo(t).Implements(scannerType) || t.Implements(valuerType)
+}
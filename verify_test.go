@@ -0,0 +1,99 @@
+package dbx
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	assert := assert.New(t)
+
+	db := NewDatabase()
+	assert.Nil(db.OpenSQLite(":memory:"))
+	defer db.Close()
+	db.DB().SetMaxOpenConns(1)
+	assert.Nil(db.RegisterTable(USER_TABLE, User{}))
+	assert.Nil(db.RegisterTable("indexed_user", IndexedUser{}))
+
+	// no tables
+	err := db.Verify()
+	schemaErr := &SchemaError{}
+	assert.True(errors.As(err, &schemaErr))
+	assert.Equal(2, len(schemaErr.Mismatches))
+	assert.Equal("indexed_user: table doesn't exist",
+		schemaErr.Mismatches[0].Error())
+
+	assert.Nil(db.CreateTables())
+	assert.Nil(db.Verify())
+
+	// tables changed by migrations
+	_, err = db.DB().Exec("DROP TABLE indexed_user")
+	assert.Nil(err)
+	_, err = db.DB().Exec("CREATE TABLE indexed_user(id INTEGER, " +
+		"userid BLOB, time INTEGER)")
+	assert.Nil(err)
+	_, err = db.DB().Exec("CREATE INDEX idx_indexed_user_userid ON " +
+		"indexed_user(userid)")
+	assert.Nil(err)
+	_, err = db.DB().Exec("CREATE INDEX idx_name_time ON indexed_user(time)")
+	assert.Nil(err)
+
+	err = db.Verify()
+	assert.True(errors.As(err, &schemaErr))
+	msgs := []string{}
+	for _, m := range schemaErr.Mismatches {
+		msgs = append(msgs, m.Error())
+	}
+	assert.Equal([]string{
+		"indexed_user.id: column isn't primary key",
+		"indexed_user.userid: column type BLOB isn't compatible with field " +
+			"type string",
+		"indexed_user.nickname: column doesn't exist",
+		"indexed_user: index idx_indexed_user_userid isn't unique",
+		"indexed_user: index idx_name_time is on (time), not (nickname,time)",
+	}, msgs)
+	assert.Contains(err.Error(), "schema of database has 5 mismatches")
+
+	mismatch := &SchemaMismatch{}
+	assert.True(errors.As(err, &mismatch))
+	assert.Equal("indexed_user", mismatch.Table)
+}
+
+func TestCompatibleType(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, c := range []struct {
+		v      interface{}
+		col    string
+		expect bool
+	}{
+		{int64(0), "INTEGER", true},
+		{int64(0), "bigint(20) unsigned", true},
+		{int64(0), "TEXT", false},
+		{uint(0), "tinyint(1)", true},
+		{"", "varchar(32)", true},
+		{"", "INTEGER", true},
+		{"", "BLOB", false},
+		{false, "tinyint(1)", true},
+		{false, "TEXT", false},
+		{0.0, "REAL", true},
+		{0.0, "decimal(10,2)", true},
+		{0.0, "TEXT", false},
+		{[]byte{}, "BLOB", true},
+		{time.Time{}, "datetime", true},
+		{time.Time{}, "INTEGER", true},
+		{time.Time{}, "REAL", false},
+		{sql.NullTime{}, "timestamp", true},
+		{new(int), "INTEGER", true},
+		{struct{}{}, "TEXT", false},
+		{struct{}{}, "", true},
+	} {
+		assert.Equal(c.expect, compatibleType(reflect.TypeOf(c.v), c.col),
+			"%T %s", c.v, c.col)
+	}
+}